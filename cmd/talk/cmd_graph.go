@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+
+	"github.com/robotalks/talk/core/cli"
+	"github.com/robotalks/talk/core/engine"
+)
+
+// GraphCommand implements robotalk graph
+type GraphCommand struct {
+	ModulesDir  []string `n:"modules-dir"`
+	LoadModules bool     `n:"load-modules"`
+	Format      string
+	Set         []string
+	Profile     []string
+	Spec        string
+}
+
+// Execute implements Executable
+func (c *GraphCommand) Execute(args []string) error {
+	if c.LoadModules {
+		loadModules(c.ModulesDir)
+	}
+	spec, err := engine.LoadSpec(c.Spec, &engine.LoadOptions{Profiles: c.Profile, Overrides: c.Set})
+	if err == nil {
+		err = spec.Resolve()
+	}
+	if err != nil {
+		return err
+	}
+	return cli.WriteGraph(os.Stdout, spec.Graph(), c.Format)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/robotalks/talk/core/engine"
+	"github.com/robotalks/talk/core/record"
+)
+
+// RecordCommand implements robotalk record
+type RecordCommand struct {
+	URL       string
+	Output    string
+	Duration  string
+	Quiet     bool
+	Endpoints []string
+}
+
+// Execute implements Executable
+func (c *RecordCommand) Execute(args []string) error {
+	var duration time.Duration
+	if c.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(c.Duration); err != nil {
+			return fmt.Errorf("invalid duration: %v", err)
+		}
+	}
+	connector, err := engine.NewConnector(c.URL)
+	if err != nil {
+		return err
+	}
+	defer connector.Close()
+	if err = connector.Connect().Wait(); err != nil {
+		return err
+	}
+
+	f, err := os.Create(c.Output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w, err := record.NewWriter(f, time.Now())
+	if err != nil {
+		return err
+	}
+	recorder := &record.Recorder{Connector: connector, Writer: w}
+	err = recorder.Watch(c.Endpoints...)
+	if err == nil {
+		waitSignal(duration)
+	}
+	if closeErr := recorder.Close(); err == nil {
+		err = closeErr
+	}
+	if !c.Quiet {
+		fmt.Fprintf(os.Stderr, "%d messages recorded to %s\n", recorder.Count(), c.Output)
+	}
+	return err
+}
+
+// ReplayCommand implements robotalk replay
+type ReplayCommand struct {
+	URL   string
+	Speed string
+	Loop  bool
+	Quiet bool
+	File  string
+}
+
+// Execute implements Executable
+func (c *ReplayCommand) Execute(args []string) error {
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(c.Speed, "x"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid speed %q", c.Speed)
+	}
+	connector, err := engine.NewConnector(c.URL)
+	if err != nil {
+		return err
+	}
+	defer connector.Close()
+	if err = connector.Connect().Wait(); err != nil {
+		return err
+	}
+
+	stopCh := make(chan struct{})
+	go func() {
+		waitSignal(0)
+		close(stopCh)
+	}()
+	player := &record.Player{Connector: connector, Speed: speed}
+	for {
+		count, err := c.play(player, stopCh)
+		if err != nil {
+			return err
+		}
+		if !c.Quiet {
+			fmt.Fprintf(os.Stderr, "%d messages replayed from %s\n", count, c.File)
+		}
+		select {
+		case <-stopCh:
+			return nil
+		default:
+		}
+		if !c.Loop {
+			return nil
+		}
+	}
+}
+
+func (c *ReplayCommand) play(player *record.Player, stopCh <-chan struct{}) (int, error) {
+	f, err := os.Open(c.File)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r, err := record.NewReader(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", c.File, err)
+	}
+	return player.Play(r, stopCh)
+}
+
+// waitSignal waits for interrupt, or the duration if it's positive
+func waitSignal(duration time.Duration) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	var timeout <-chan time.Time
+	if duration > 0 {
+		timeout = time.After(duration)
+	}
+	select {
+	case <-sigCh:
+	case <-timeout:
+	}
+}
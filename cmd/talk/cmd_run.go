@@ -3,6 +3,9 @@ package main
 import (
 	"os"
 
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/robotalks/talk/core/broker"
+	"github.com/robotalks/talk/core/cli"
 	"github.com/robotalks/talk/core/engine"
 )
 
@@ -11,15 +14,51 @@ type RunCommand struct {
 	URL         string
 	ModulesDir  []string `n:"modules-dir"`
 	LoadModules bool     `n:"load-modules"`
+	Set         []string
+	Profile     []string
 	Quiet       bool
+	Watch       bool
+	PrintOrder  bool   `n:"print-order"`
+	LogFormat   string `n:"log-format"`
+	LogLevel    string `n:"log-level"`
+	Metrics     string
+	Broker      string `n:"embedded-broker"`
 	Spec        string
 }
 
 // Execute implements Executable
 func (c *RunCommand) Execute(args []string) error {
+	if c.Broker != "" {
+		c.URL = broker.ClientURL(c.Broker)
+	}
 	os.Setenv("MQHUB_URL", c.URL)
 	if c.LoadModules {
 		loadModules(c.ModulesDir)
 	}
-	return engine.Run(c.URL, c.Spec)
+	runner := engine.NewRunner(c.URL, c.Spec)
+	output, err := engine.NewLogOutput(os.Stderr, c.LogFormat)
+	if err != nil {
+		return err
+	}
+	if output.Level, err = v0.ParseLogLevel(c.LogLevel); err != nil {
+		return err
+	}
+	if c.Quiet {
+		output.Writer = nil
+	}
+	runner.LogOutput = output
+	runner.MetricsAddr = c.Metrics
+	runner.EmbeddedBroker = c.Broker
+	runner.Overrides = c.Set
+	runner.Profiles = c.Profile
+	runner.Watch = c.Watch
+	if c.PrintOrder {
+		if err := runner.Load(); err != nil {
+			return err
+		}
+		if err := cli.PrintInitOrder(os.Stdout, runner.Spec); err != nil {
+			return err
+		}
+	}
+	return runner.Run()
 }
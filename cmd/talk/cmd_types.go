@@ -10,6 +10,7 @@ import (
 type TypesCommand struct {
 	ModulesDir  []string `n:"modules-dir"`
 	LoadModules bool     `n:"load-modules"`
+	JSON        bool     `n:"json"`
 }
 
 // Execute implements Executable
@@ -17,6 +18,24 @@ func (c *TypesCommand) Execute(args []string) error {
 	if c.LoadModules {
 		loadModules(c.ModulesDir)
 	}
+	if c.JSON {
+		return cli.PrintTypesJSON(os.Stdout)
+	}
 	cli.PrintTypes(os.Stdout)
 	return nil
 }
+
+// DescribeCommand implements robotalk describe
+type DescribeCommand struct {
+	ModulesDir  []string `n:"modules-dir"`
+	LoadModules bool     `n:"load-modules"`
+	Type        string
+}
+
+// Execute implements Executable
+func (c *DescribeCommand) Execute(args []string) error {
+	if c.LoadModules {
+		loadModules(c.ModulesDir)
+	}
+	return cli.DescribeType(os.Stdout, c.Type)
+}
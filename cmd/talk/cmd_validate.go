@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/robotalks/talk/core/engine"
+)
+
+// ValidateCommand implements robotalk validate
+type ValidateCommand struct {
+	ModulesDir  []string `n:"modules-dir"`
+	LoadModules bool     `n:"load-modules"`
+	Set         []string
+	Profile     []string
+	Spec        string
+}
+
+// Execute implements Executable
+func (c *ValidateCommand) Execute(args []string) error {
+	if c.LoadModules {
+		loadModules(c.ModulesDir)
+	}
+	spec, err := engine.LoadSpec(c.Spec, &engine.LoadOptions{Profiles: c.Profile, Overrides: c.Set})
+	if err == nil {
+		err = spec.Resolve()
+	}
+	if err == nil {
+		err = spec.Validate()
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%s: OK\n", c.Spec)
+	return nil
+}
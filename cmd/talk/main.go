@@ -44,7 +44,7 @@ func main() {
 				&flag.Option{
 					Name:    "url",
 					Alias:   []string{"s"},
-					Desc:    "mqhub URL",
+					Desc:    "mqhub URL (mqtt://host:port, mem://)",
 					Default: mqURL,
 				},
 				&flag.Option{
@@ -70,6 +70,110 @@ func main() {
 				&flag.Command{
 					Name: "run",
 					Desc: "Run Components",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name:  "profile",
+							Alias: []string{"p"},
+							Desc:  "Apply the named profile in spec",
+							List:  true,
+						},
+						&flag.Option{
+							Name: "set",
+							Desc: "Override spec value, e.g. components.servo.config.pin=12",
+							List: true,
+							Tags: map[string]interface{}{"help-var": "PATH=VALUE"},
+						},
+						&flag.Option{
+							Name:  "watch",
+							Alias: []string{"w"},
+							Desc:  "Reload the spec file when it changes",
+							Type:  "bool",
+						},
+						&flag.Option{
+							Name: "print-order",
+							Desc: "Print the init order before starting",
+							Type: "bool",
+						},
+						&flag.Option{
+							Name: "metrics",
+							Desc: "Serve Prometheus metrics on /metrics at the address, e.g. :9100",
+							Tags: map[string]interface{}{"help-var": "ADDR"},
+						},
+						&flag.Option{
+							Name: "embedded-broker",
+							Desc: "Host an MQTT broker in process at the address, e.g. :1883, and connect to it",
+							Tags: map[string]interface{}{"help-var": "ADDR"},
+						},
+						&flag.Option{
+							Name:    "log-format",
+							Desc:    "Log output format (text, json)",
+							Default: "text",
+						},
+						&flag.Option{
+							Name:    "log-level",
+							Desc:    "Default log level (debug, info, warn, error)",
+							Default: "info",
+						},
+					},
+					Arguments: []*flag.Option{
+						&flag.Option{
+							Name:     "spec",
+							Desc:     "Components spec file",
+							Required: true,
+							Type:     "string",
+							Tags:     map[string]interface{}{"help-var": "SPEC"},
+						},
+					},
+				},
+				&flag.Command{
+					Name: "validate",
+					Desc: "Validate Components spec without running",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name:  "profile",
+							Alias: []string{"p"},
+							Desc:  "Apply the named profile in spec",
+							List:  true,
+						},
+						&flag.Option{
+							Name: "set",
+							Desc: "Override spec value, e.g. components.servo.config.pin=12",
+							List: true,
+							Tags: map[string]interface{}{"help-var": "PATH=VALUE"},
+						},
+					},
+					Arguments: []*flag.Option{
+						&flag.Option{
+							Name:     "spec",
+							Desc:     "Components spec file",
+							Required: true,
+							Type:     "string",
+							Tags:     map[string]interface{}{"help-var": "SPEC"},
+						},
+					},
+				},
+				&flag.Command{
+					Name: "graph",
+					Desc: "Render the components graph",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name:    "format",
+							Alias:   []string{"f"},
+							Desc:    "Output format (dot, mermaid)",
+							Default: "dot",
+						},
+						&flag.Option{
+							Name:  "profile",
+							Alias: []string{"p"},
+							Desc:  "Apply the named profile in spec",
+							List:  true,
+						},
+						&flag.Option{
+							Name: "set",
+							Desc: "Override spec value, e.g. components.servo.config.pin=12",
+							List: true,
+						},
+					},
 					Arguments: []*flag.Option{
 						&flag.Option{
 							Name:     "spec",
@@ -83,6 +187,78 @@ func main() {
 				&flag.Command{
 					Name: "types",
 					Desc: "List all known types",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name: "json",
+							Desc: "Print types with schemas in JSON",
+							Type: "bool",
+						},
+					},
+				},
+				&flag.Command{
+					Name: "describe",
+					Desc: "Show config, injections and endpoints of a type",
+					Arguments: []*flag.Option{
+						&flag.Option{
+							Name:     "type",
+							Desc:     "Component type name",
+							Required: true,
+							Type:     "string",
+							Tags:     map[string]interface{}{"help-var": "TYPE"},
+						},
+					},
+				},
+				&flag.Command{
+					Name: "record",
+					Desc: "Record messages of endpoints to a file",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name:     "output",
+							Alias:    []string{"o"},
+							Desc:     "Record file to write",
+							Required: true,
+							Tags:     map[string]interface{}{"help-var": "FILE"},
+						},
+						&flag.Option{
+							Name: "duration",
+							Desc: "Stop recording after the duration, e.g. 10m",
+						},
+					},
+					Arguments: []*flag.Option{
+						&flag.Option{
+							Name:     "endpoints",
+							Desc:     "Endpoints to record, e.g. robot/vision/objects",
+							Required: true,
+							List:     true,
+							Type:     "string",
+							Tags:     map[string]interface{}{"help-var": "COMPONENT/ENDPOINT"},
+						},
+					},
+				},
+				&flag.Command{
+					Name: "replay",
+					Desc: "Publish recorded messages with the original timing",
+					Options: []*flag.Option{
+						&flag.Option{
+							Name:    "speed",
+							Desc:    "Speed factor of the timing, 0 to publish without waiting",
+							Default: "1",
+						},
+						&flag.Option{
+							Name: "loop",
+							Desc: "Replay repeatedly until interrupted",
+							Type: "bool",
+						},
+					},
+					Arguments: []*flag.Option{
+						&flag.Option{
+							Name:     "file",
+							Desc:     "Record file",
+							Required: true,
+							Type:     "string",
+							Tags:     map[string]interface{}{"help-var": "FILE"},
+						},
+					},
 				},
 				&flag.Command{
 					Name: "version",
@@ -95,7 +271,12 @@ func main() {
 	cmd.Use(term.NewExt()).
 		Use(bind.NewExt().
 			Bind(&RunCommand{}, "run").
+			Bind(&ValidateCommand{}, "validate").
+			Bind(&GraphCommand{}, "graph").
 			Bind(&TypesCommand{}, "types").
+			Bind(&DescribeCommand{}, "describe").
+			Bind(&RecordCommand{}, "record").
+			Bind(&ReplayCommand{}, "replay").
 			Bind(&versionCommand{}, "version")).
 		Use(help.NewExt()).
 		Parse().
@@ -15,8 +15,8 @@ type Component struct {
 	Args    []string `map:"args"`
 	WorkDir string   `map:"workdir"`
 
-	ref v0.ComponentRef
-	cmd *exec.Cmd
+	ref  v0.ComponentRef
+	proc *cmn.Process
 }
 
 // NewComponent creates a Component
@@ -46,16 +46,22 @@ func (s *Component) Start() error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Start()
+	proc, err := cmn.StartProcess(cmd, func(err error) {
+		eng.ReportExit(s, err)
+	})
 	if err == nil {
-		s.cmd = cmd
+		s.proc = proc
 	}
 	return err
 }
 
 // Stop implements v0.LifecycleCtl
 func (s *Component) Stop() error {
-	return cmn.StopCmd(s.cmd)
+	if s.proc == nil {
+		// not started or failed to start
+		return nil
+	}
+	return s.proc.Stop()
 }
 
 // Type is the Component type
@@ -64,4 +70,5 @@ var Type = eng.DefineComponentType("cmd",
 		return NewComponent(ref)
 	})).
 	Describe("[BuiltIn] Execute External Program").
+	Prototype(&Component{}).
 	Register()
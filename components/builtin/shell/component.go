@@ -15,8 +15,8 @@ type Component struct {
 	Shell   []string `map:"shell"`
 	WorkDir string   `map:"workdir"`
 
-	ref v0.ComponentRef
-	cmd *exec.Cmd
+	ref  v0.ComponentRef
+	proc *cmn.Process
 }
 
 // NewComponent creates a Component
@@ -49,16 +49,22 @@ func (s *Component) Start() error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	err := cmd.Start()
+	proc, err := cmn.StartProcess(cmd, func(err error) {
+		eng.ReportExit(s, err)
+	})
 	if err == nil {
-		s.cmd = cmd
+		s.proc = proc
 	}
 	return err
 }
 
 // Stop implements v0.LifecycleCtl
 func (s *Component) Stop() error {
-	return cmn.StopCmd(s.cmd)
+	if s.proc == nil {
+		// not started or failed to start
+		return nil
+	}
+	return s.proc.Stop()
 }
 
 // Type is the Component type
@@ -67,4 +73,5 @@ var Type = eng.DefineComponentType("shell",
 		return NewComponent(ref)
 	})).
 	Describe("[BuiltIn] Execute Shell Command").
+	Prototype(&Component{}).
 	Register()
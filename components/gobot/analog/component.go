@@ -13,9 +13,9 @@ import (
 
 // Config defines analog sensor configuration
 type Config struct {
-	Pin      string `map:"pin"`
-	Div      int    `map:"div"`
-	Interval int    `map:"interval"`
+	Pin      string       `map:"pin"`
+	Div      int          `map:"div"`
+	Interval eng.Duration `map:"interval"`
 }
 
 // Component is the implement of analog sensor Component
@@ -32,7 +32,7 @@ type Component struct {
 // NewComponent creates a Component
 func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 	s := &Component{
-		Config: Config{Interval: 1000},
+		Config: Config{Interval: eng.Duration(time.Second)},
 		ref:    ref,
 		state:  &mqhub.DataPoint{Name: "value", Retain: true},
 	}
@@ -43,7 +43,7 @@ func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 	if !ok {
 		return nil, fmt.Errorf("injection adapter of %s is not gobot.aio.AnalogReader", ref.MessagePath())
 	}
-	s.device = aio.NewAnalogSensorDriver(conn, s.Pin, time.Duration(s.Interval)*time.Millisecond)
+	s.device = aio.NewAnalogSensorDriver(conn, s.Pin, s.Interval.Duration())
 	s.device.On(aio.Data, func(v interface{}) {
 		if val, ok := v.(int); ok {
 			s.report(val)
@@ -97,4 +97,6 @@ var Type = eng.DefineComponentType("gobot.analog",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] Analog Sensor").
+	Prototype(&Component{}).
+	DataPoint("value", int(0)).
 	Register()
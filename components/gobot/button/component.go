@@ -97,4 +97,6 @@ var Type = eng.DefineComponentType("gobot.gpio.button",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] GPIO Button").
+	Prototype(&Component{}).
+	DataPoint("state", int(0)).
 	Register()
@@ -0,0 +1,30 @@
+package button
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robotalks/talk/core/enginetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestButton(t *testing.T) {
+	adaptor := enginetest.NewAdaptor("gpio")
+	comp, err := enginetest.NewRef("button").
+		WithConfig(map[string]interface{}{"pin": "2", "reverse": true}).
+		Inject("gpio", adaptor).
+		Create(Type)
+	assert.NoError(t, err)
+	probe := enginetest.NewProbe(comp)
+	defer probe.Close()
+	button := comp.(*Component)
+	assert.NoError(t, button.Start())
+	defer button.Stop()
+
+	adaptor.SetDigital("2", 1)
+	state := probe.DataPoint("state")
+	assert.NoError(t, state.WaitLen(1, time.Second))
+	var v int
+	assert.NoError(t, state.Last(&v))
+	assert.Equal(t, 0, v)
+}
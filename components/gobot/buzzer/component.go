@@ -155,4 +155,7 @@ var Type = eng.DefineComponentType("gobot.gpio.buzzer",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] GPIO Buzzer (Digital Pin)").
+	Prototype(&Component{}).
+	Reactor("seq", []float32{}).
+	DataPoint("playing", false).
 	Register()
@@ -12,4 +12,5 @@ import (
 	_ "github.com/robotalks/talk/components/gobot/pin"
 	_ "github.com/robotalks/talk/components/gobot/raspi"
 	_ "github.com/robotalks/talk/components/gobot/servo/pwm"
+	_ "github.com/robotalks/talk/components/gobot/sim"
 )
@@ -2,7 +2,7 @@ package pca9685
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"time"
 
 	"github.com/robotalks/mqhub.go/mqhub"
@@ -14,8 +14,8 @@ import (
 
 // Config defines servo configuration
 type Config struct {
-	Freq uint `map:"frequency"`
-	Wait int  `map:"wait"`
+	Freq eng.Frequency `map:"frequency"`
+	Wait eng.Duration  `map:"wait"`
 }
 
 // State defines the state of this component
@@ -38,7 +38,7 @@ type Component struct {
 // NewComponent creates a Component
 func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 	s := &Component{
-		Config: Config{Freq: 50, Wait: 0},
+		Config: Config{Freq: 50},
 		ref:    ref,
 		state:  &mqhub.DataPoint{Name: "state", Retain: true},
 	}
@@ -77,7 +77,7 @@ func (s *Component) Start() (err error) {
 	if err = s.device.Start(); err != nil {
 		return
 	}
-	return s.SetPWMFrequency(s.Freq)
+	return s.SetPWMFrequency(uint(math.Round(s.Freq.Hz())))
 }
 
 // Stop implements v0.LifecycleCtl
@@ -88,11 +88,11 @@ func (s *Component) Stop() error {
 // SetPWMFrequency implements common.PWMDriver
 func (s *Component) SetPWMFrequency(freq uint) error {
 	if err := s.device.SetPWMFreq(float32(freq)); err != nil {
-		log.Printf("[%s] SetPWMFrequency(%d) err: %v", s.ref.ComponentID(), freq, err)
+		v0.LoggerOf(s.ref).Error("set PWM frequency failed", "freq", freq, "err", err)
 		return err
 	}
 	if s.Wait > 0 {
-		time.Sleep(time.Duration(s.Wait) * time.Millisecond)
+		time.Sleep(s.Wait.Duration())
 	}
 	s.state.Update(&State{Freq: freq})
 	return nil
@@ -111,8 +111,8 @@ type setPulseParams struct {
 
 func (s *Component) setPulse(params *setPulseParams) {
 	if err := s.SetPWMPulse(params.Ch, params.On, params.Off); err != nil {
-		log.Printf("[%s] SetPulse(%d, %d, %d) err: %v",
-			s.ref.ComponentID(), params.Ch, params.On, params.Off, err)
+		v0.LoggerOf(s.ref).Error("set pulse failed",
+			"channel", params.Ch, "on", params.On, "off", params.Off, "err", err)
 	}
 }
 
@@ -122,4 +122,8 @@ var Type = eng.DefineComponentType("gobot.drv.pca9685",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] PCA9685 Driver (I2C)").
+	Prototype(&Component{}).
+	DataPoint("state", State{}).
+	Reactor("freq", uint(0)).
+	Reactor("pulse", setPulseParams{}).
 	Register()
@@ -79,4 +79,5 @@ var Type = eng.DefineComponentType("gobot.adapter.firmata",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] Firmata Adapter").
+	Prototype(&Component{}).
 	Register()
@@ -83,4 +83,7 @@ var Type = eng.DefineComponentType("gobot.gpio.led",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] GPIO LED").
+	Prototype(&Component{}).
+	DataPoint("state", State{}).
+	Reactor("power", State{}).
 	Register()
@@ -0,0 +1,29 @@
+package led
+
+import (
+	"testing"
+
+	"github.com/robotalks/talk/core/enginetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLED(t *testing.T) {
+	adaptor := enginetest.NewAdaptor("gpio")
+	comp, err := enginetest.NewRef("led").Set("pin", "13").Inject("gpio", adaptor).Create(Type)
+	assert.NoError(t, err)
+	probe := enginetest.NewProbe(comp)
+	defer probe.Close()
+
+	brightness := byte(64)
+	assert.NoError(t, probe.Send("power", State{On: true}))
+	assert.NoError(t, probe.Send("power", State{On: true, Brightness: &brightness}))
+	assert.NoError(t, probe.Send("power", State{On: false}))
+	assert.Equal(t, []enginetest.PinWrite{
+		{Kind: enginetest.WriteDigital, Pin: "13", Value: 1},
+		{Kind: enginetest.WritePWM, Pin: "13", Value: 64},
+		{Kind: enginetest.WriteDigital, Pin: "13", Value: 0},
+	}, adaptor.Writes())
+
+	_, err = enginetest.NewRef("led").Set("pin", "13").Create(Type)
+	assert.Error(t, err)
+}
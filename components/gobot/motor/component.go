@@ -96,4 +96,7 @@ var Type = eng.DefineComponentType("gobot.gpio.motor",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] GPIO Motor").
+	Prototype(&Component{}).
+	DataPoint("state", float32(0)).
+	Reactor("speed", float32(0)).
 	Register()
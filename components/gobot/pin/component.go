@@ -107,4 +107,7 @@ var Type = eng.DefineComponentType("gobot.gpio.pin",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] GPIO Pin").
+	Prototype(&Component{}).
+	Reactor("on", false).
+	Reactor("pwm", byte(0)).
 	Register()
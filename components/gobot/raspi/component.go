@@ -40,4 +40,5 @@ var Type = eng.DefineComponentType("gobot.adapter.raspi",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] Raspberry Pi Adapter").
+	Prototype(&Component{}).
 	Register()
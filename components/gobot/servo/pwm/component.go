@@ -2,7 +2,6 @@ package pwm
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/robotalks/mqhub.go/mqhub"
 	cmn "github.com/robotalks/talk/components/gobot/common"
@@ -91,8 +90,8 @@ func (s *Component) SetServoPos(pos float32) error {
 	}
 	pulse := uint(s.PulseMin + int((pos+1.0)*float32(s.PulseMax-s.PulseMin)/2.0))
 	if err := s.Driver.SetPWMPulse(s.Channel, 0, pulse); err != nil {
-		log.Printf("[%s] SetPosition(%f)[chn=%d, pulse=%d] err: %v",
-			s.ref.ComponentID(), pos, s.Channel, pulse, err)
+		v0.LoggerOf(s.ref).Error("set position failed",
+			"pos", pos, "channel", s.Channel, "pulse", pulse, "err", err)
 		return err
 	}
 
@@ -104,8 +103,8 @@ func (s *Component) SetServoPos(pos float32) error {
 func (s *Component) setPulse(value int) {
 	pulse := uint(value)
 	if err := s.Driver.SetPWMPulse(s.Channel, 0, pulse); err != nil {
-		log.Printf("[%s] SetPulse(%d)[chn=%d] err: %v",
-			s.ref.ComponentID(), pulse, s.Channel, err)
+		v0.LoggerOf(s.ref).Error("set pulse failed",
+			"pulse", pulse, "channel", s.Channel, "err", err)
 		return
 	}
 	pos := float32(int(pulse)-s.PulseMin)*2.0/float32(s.PulseMax-s.PulseMin) - 1.0
@@ -125,4 +124,8 @@ var Type = eng.DefineComponentType("gobot.servo.pwm",
 		return NewComponent(ref)
 	})).
 	Describe("[GoBot] PWM Servo").
+	Prototype(&Component{}).
+	DataPoint("state", State{}).
+	Reactor("pos", float32(0)).
+	Reactor("pulse", int(0)).
 	Register()
@@ -0,0 +1,154 @@
+package sim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	eng "github.com/robotalks/talk/core/engine"
+	"github.com/robotalks/talk/core/enginetest"
+	"gobot.io/x/gobot"
+)
+
+// DefaultPressDuration is the duration of a press without duration
+const DefaultPressDuration = 100 * time.Millisecond
+
+// Config defines simulated adapter configuration
+type Config struct {
+	Digital map[string]int `map:"digital"`
+	Analog  map[string]int `map:"analog"`
+	Bus     int            `map:"bus"`
+}
+
+// Component is the implement of simulated adapter Component
+type Component struct {
+	Config
+
+	ref     v0.ComponentRef
+	adaptor *enginetest.Adaptor
+	pins    *mqhub.DataPoint
+	i2c     *mqhub.DataPoint
+	digital *mqhub.Reactor
+	analog  *mqhub.Reactor
+	press   *mqhub.Reactor
+}
+
+// PinValue sets the value of a pin
+type PinValue struct {
+	Pin   string `json:"pin"`
+	Value int    `json:"value"`
+}
+
+// Press sets a digital pin high for the duration in milliseconds
+type Press struct {
+	Pin      string `json:"pin"`
+	Duration int    `json:"duration"`
+}
+
+// NewComponent creates a new Component
+func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
+	s := &Component{
+		ref:  ref,
+		pins: &mqhub.DataPoint{Name: "pins", Retain: true},
+		i2c:  &mqhub.DataPoint{Name: "i2c", Retain: true},
+	}
+	s.digital = mqhub.ReactorAs("digital", s.setDigital)
+	s.analog = mqhub.ReactorAs("analog", s.setAnalog)
+	s.press = mqhub.ReactorAs("press", s.Press)
+	if err := eng.SetupComponent(s, ref); err != nil {
+		return nil, err
+	}
+	s.adaptor = enginetest.NewAdaptor(ref.ComponentID())
+	s.adaptor.Bus = s.Bus
+	for pin, level := range s.Digital {
+		s.adaptor.SetDigital(pin, level)
+	}
+	for pin, value := range s.Analog {
+		s.adaptor.SetAnalog(pin, value)
+	}
+	s.adaptor.OnPinChange = func() { s.pins.Update(s.adaptor.Pins()) }
+	s.adaptor.OnRegisterChange = func() { s.i2c.Update(s.adaptor.Registers()) }
+	return s, nil
+}
+
+// Ref implements v0.Component
+func (s *Component) Ref() v0.ComponentRef {
+	return s.ref
+}
+
+// Type implements v0.Component
+func (s *Component) Type() v0.ComponentType {
+	return Type
+}
+
+// Endpoints implements v0.Stateful
+func (s *Component) Endpoints() []mqhub.Endpoint {
+	return []mqhub.Endpoint{s.pins, s.i2c, s.digital, s.analog, s.press}
+}
+
+// Start implements v0.LifecycleCtl
+func (s *Component) Start() error {
+	s.pins.Update(s.adaptor.Pins())
+	s.i2c.Update(s.adaptor.Registers())
+	return s.adaptor.Connect()
+}
+
+// Stop implements v0.LifecycleCtl
+func (s *Component) Stop() error {
+	return s.adaptor.Finalize()
+}
+
+// Adaptor implements cmn.Adapter
+func (s *Component) Adaptor() gobot.Adaptor {
+	return s.adaptor
+}
+
+// SimAdaptor returns the simulated adaptor
+func (s *Component) SimAdaptor() *enginetest.Adaptor {
+	return s.adaptor
+}
+
+// Press sets the pin high and releases it after the duration
+func (s *Component) Press(p Press) error {
+	if p.Pin == "" {
+		return fmt.Errorf("press: pin required")
+	}
+	duration := time.Duration(p.Duration) * time.Millisecond
+	if duration <= 0 {
+		duration = DefaultPressDuration
+	}
+	s.adaptor.SetDigital(p.Pin, 1)
+	time.AfterFunc(duration, func() { s.adaptor.SetDigital(p.Pin, 0) })
+	return nil
+}
+
+func (s *Component) setDigital(v PinValue) error {
+	if v.Pin == "" {
+		return fmt.Errorf("digital: pin required")
+	}
+	s.adaptor.SetDigital(v.Pin, v.Value)
+	return nil
+}
+
+func (s *Component) setAnalog(v PinValue) error {
+	if v.Pin == "" {
+		return fmt.Errorf("analog: pin required")
+	}
+	s.adaptor.SetAnalog(v.Pin, v.Value)
+	return nil
+}
+
+// Type is the Component type
+var Type = eng.DefineComponentType("gobot.adapter.sim",
+	eng.ComponentFactoryFunc(func(ref v0.ComponentRef) (v0.Component, error) {
+		return NewComponent(ref)
+	})).
+	Describe("[GoBot] Simulated Adapter of in-memory pins and I2C registers").
+	Prototype(&Component{}).
+	DataPoint("pins", enginetest.PinState{}).
+	DataPoint("i2c", map[string]map[string]int{}).
+	Reactor("digital", PinValue{}).
+	Reactor("analog", PinValue{}).
+	Reactor("press", Press{}).
+	Register()
@@ -0,0 +1,171 @@
+package sim
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	_ "github.com/robotalks/talk/components/gobot/analog"
+	_ "github.com/robotalks/talk/components/gobot/button"
+	_ "github.com/robotalks/talk/components/gobot/drv/pca9685"
+	_ "github.com/robotalks/talk/components/gobot/led"
+	_ "github.com/robotalks/talk/components/gobot/motor"
+	eng "github.com/robotalks/talk/core/engine"
+	"github.com/robotalks/talk/core/enginetest"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSpec = `---
+name: robot
+components:
+  sim:
+    type: gobot.adapter.sim
+    config:
+      analog:
+        A0: 512
+  led:
+    type: gobot.gpio.led
+    config:
+      pin: "13"
+    inject:
+      gpio:
+        type: ref
+        id: sim
+  button:
+    type: gobot.gpio.button
+    config:
+      pin: "7"
+    inject:
+      gpio:
+        type: ref
+        id: sim
+  motor:
+    type: gobot.gpio.motor
+    config:
+      pin: "5"
+    inject:
+      gpio:
+        type: ref
+        id: sim
+  light:
+    type: gobot.analog
+    config:
+      pin: A0
+      interval: 10ms
+    inject:
+      io:
+        type: ref
+        id: sim
+  pwm:
+    type: gobot.drv.pca9685
+    inject:
+      i2c:
+        type: ref
+        id: sim
+`
+
+func watchValues(t *testing.T, conn mqhub.Connector, component, endpoint string) <-chan int {
+	ch := make(chan int, 16)
+	_, err := conn.Describe(component).Endpoint(endpoint).Watch(mqhub.MessageSinkAs(func(v int) {
+		ch <- v
+	}))
+	assert.NoError(t, err)
+	return ch
+}
+
+func expectValue(t *testing.T, ch <-chan int, expected int) {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case v := <-ch:
+			if v == expected {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("expect value %d", expected)
+		}
+	}
+}
+
+func send(t *testing.T, conn mqhub.Connector, component, endpoint string, v interface{}) {
+	assert.NoError(t, conn.Describe(component).Endpoint(endpoint).ConsumeMessage(mqhub.MsgFrom(v)).Wait())
+}
+
+func TestSimAdapter(t *testing.T) {
+	conf := eng.NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(testSpec)))
+	spec, err := eng.ParseSpec(conf)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Resolve())
+	conn := memhub.NewHub("sim").Connector()
+	assert.NoError(t, spec.Connect(conn))
+
+	button := watchValues(t, conn, "robot/button", "state")
+	light := watchValues(t, conn, "robot/light", "value")
+	assert.NoError(t, spec.Start())
+	defer spec.Disconnect()
+	expectValue(t, light, 512)
+
+	var sim *Component
+	for _, comp := range spec.ChildSpecs {
+		if s, ok := comp.Instance.(*Component); ok {
+			sim = s
+		}
+	}
+	if !assert.NotNil(t, sim) {
+		return
+	}
+	adaptor := sim.SimAdaptor()
+
+	var (
+		pins     enginetest.PinState
+		pinsLock sync.Mutex
+	)
+	_, err = conn.Describe("robot/sim").Endpoint("pins").Watch(mqhub.MessageSinkAs(func(state enginetest.PinState) {
+		pinsLock.Lock()
+		pins = state
+		pinsLock.Unlock()
+	}))
+	assert.NoError(t, err)
+	send(t, conn, "robot/led", "power", map[string]interface{}{"on": true})
+	pinsLock.Lock()
+	assert.Equal(t, 1, pins.Digital["13"])
+	pinsLock.Unlock()
+
+	send(t, conn, "robot/motor", "speed", 0.5)
+	assert.Equal(t, 127, adaptor.Pins().PWM["5"])
+
+	send(t, conn, "robot/sim", "press", Press{Pin: "7", Duration: 50})
+	expectValue(t, button, 1)
+	expectValue(t, button, 0)
+
+	send(t, conn, "robot/sim", "analog", PinValue{Pin: "A0", Value: 300})
+	expectValue(t, light, 300)
+
+	assert.Equal(t, byte(0x79), adaptor.I2cDevice(0x40).Register(0xfe))
+	send(t, conn, "robot/pwm", "pulse", map[string]interface{}{"ch": 0, "on": 0, "off": 300})
+	assert.Equal(t, byte(0x2c), adaptor.I2cDevice(0x40).Register(0x08))
+	assert.Equal(t, byte(0x01), adaptor.I2cDevice(0x40).Register(0x09))
+	assert.Equal(t, 0x2c, adaptor.Registers()["0x40"]["0x08"])
+}
+
+func TestSimDevice(t *testing.T) {
+	a := enginetest.NewAdaptor("sim")
+	changes := 0
+	a.OnRegisterChange = func() { changes++ }
+	conn, err := a.GetConnection(0x20, a.GetDefaultBus())
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte{0x10, 1, 2, 3})
+	assert.NoError(t, err)
+	assert.NoError(t, conn.WriteByte(0x11))
+	b, err := conn.ReadByte()
+	assert.NoError(t, err)
+	assert.Equal(t, byte(2), b)
+	w, err := conn.ReadWordData(0x11)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(0x0302), w)
+	assert.Equal(t, 1, changes)
+}
@@ -1,52 +1,24 @@
 package camera
 
 import (
-	"fmt"
-	"io"
-	"log"
-
-	"github.com/denisbrodbeck/machineid"
-
 	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/components/v4l/stream"
 	"github.com/robotalks/talk/contract/v0"
-	cmn "github.com/robotalks/talk/core/common"
 	eng "github.com/robotalks/talk/core/engine"
 )
 
 // Config defines camera configuration
 type Config struct {
-	Device  string            `map:"device"`
-	Width   int               `map:"width"`
-	Height  int               `map:"height"`
-	Format  string            `map:"format"`
-	Quality *int              `map:"quality"`
-	AutoOn  bool              `map:"auto-on"`
-	WithSeq bool              `map:"with-seq"`
-	SeqSrc  string            `map:"seq-source"`
-	Casts   map[string]string `map:"cast"`
-}
-
-// State defines camera state
-type State struct {
-	On     bool   `json:"on"`
-	Width  int    `json:"width,omitempty"`
-	Height int    `json:"height,omitempty"`
-	FourCC FourCC `json:"fourcc"`
+	Device string `map:"device"`
+	Format string `map:"format"`
+	stream.Config
 }
 
 // Component is the implementation
 type Component struct {
-	ref      v0.ComponentRef
-	config   Config
-	settings Options
-	stateDp  *mqhub.DataPoint
-	recvDp   *mqhub.DataPoint
-	imageDp  *mqhub.DataPoint
-	onOff    *mqhub.Reactor
-	castTo   *mqhub.Reactor
-	udpCast  *cmn.UDPCast
-	casts    []cmn.CastTarget
-	stream   *Stream
+	ref    v0.ComponentRef
+	config Config
+	caster *stream.Caster
 }
 
 // NewComponent creates a Component
@@ -55,12 +27,12 @@ func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 		ref: ref,
 		config: Config{
 			Device: "/dev/video0",
-			Width:  640,
-			Height: 480,
 			Format: FourCCMJPG.String(),
+			Config: stream.Config{
+				Width:  640,
+				Height: 480,
+			},
 		},
-		stateDp: &mqhub.DataPoint{Name: "state", Retain: true},
-		recvDp:  &mqhub.DataPoint{Name: "receiver", Retain: true},
 	}
 	mapConf := &eng.MapConfig{Map: ref.ComponentConfig()}
 	err := mapConf.As(&s.config)
@@ -68,45 +40,25 @@ func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 		return nil, err
 	}
 
-	if s.config.WithSeq && s.config.SeqSrc == "" {
-		if s.config.SeqSrc, err = machineid.ID(); err != nil {
-			return nil, fmt.Errorf("fail to get machine-id: %v", err)
-		}
-	}
-
-	s.onOff = mqhub.ReactorAs("on", s.setOn)
-	s.castTo = mqhub.ReactorAs("cast", s.setCastTo)
-
-	s.settings.Device = s.config.Device
-	s.settings.FourCC, err = ParseFourCC(s.config.Format)
+	var settings Options
+	settings.Device = s.config.Device
+	settings.FourCC, err = ParseFourCC(s.config.Format)
 	if err != nil {
 		return nil, err
 	}
-	s.settings.Width, s.settings.Height = s.config.Width, s.config.Height
-	s.settings.Quality = s.config.Quality
-
-	if s.settings.FourCC == FourCCMJPG && s.config.WithSeq {
-		s.settings.WithSeq = s.config.WithSeq
-		s.settings.SeqSrc = s.config.SeqSrc
+	if settings.FourCC != FourCCMJPG {
+		s.config.WithSeq = false
 	}
-
-	s.udpCast = &cmn.UDPCast{}
-	s.casts = []cmn.CastTarget{s.udpCast}
-
-	for t, val := range s.config.Casts {
-		switch t {
-		case "udp":
-			s.casts = append(s.casts, &cmn.UDPCast{Address: val})
-		case "endpoint":
-			s.imageDp = &mqhub.DataPoint{Name: val}
-			s.casts = append(s.casts, &cmn.DataPointCast{DP: s.imageDp})
-		default:
-			return nil, fmt.Errorf("unknown cast type %s", t)
-		}
+	s.caster, err = stream.NewCaster(s, &s.config.Config, settings, OpenCamera)
+	if err != nil {
+		return nil, err
 	}
+	return s, nil
+}
 
-	s.stream = &Stream{Casts: s.casts}
-	return s, err
+// RegisterMetrics implements v0.Instrumented
+func (s *Component) RegisterMetrics(m v0.Metrics) {
+	s.caster.RegisterMetrics(m)
 }
 
 // Ref implements v0.Component
@@ -120,72 +72,18 @@ func (s *Component) Type() v0.ComponentType {
 }
 
 // Endpoints implements v0.Stateful
-func (s *Component) Endpoints() (endpoints []mqhub.Endpoint) {
-	endpoints = []mqhub.Endpoint{s.onOff, s.castTo, s.stateDp, s.recvDp}
-	if s.imageDp != nil {
-		endpoints = append(endpoints, s.imageDp)
-	}
-	return
+func (s *Component) Endpoints() []mqhub.Endpoint {
+	return s.caster.Endpoints()
 }
 
 // Start implements v0.LifecycleCtl
 func (s *Component) Start() error {
-	for _, c := range s.casts {
-		if udpCast, ok := c.(*cmn.UDPCast); ok {
-			if err := udpCast.Dial(); err != nil {
-				return fmt.Errorf("start UDP cast error: %v", err)
-			}
-		}
-	}
-	s.stream.Start()
-	s.stateDp.Update(&State{})
-	s.recvDp.Update("")
-	log.Printf("[%s] Auto On: %v", s.ref.ComponentID(), s.config.AutoOn)
-	if s.config.AutoOn {
-		s.setOn(true)
-	}
-	return nil
+	return s.caster.Start()
 }
 
 // Stop implements v0.LifecycleCtl
 func (s *Component) Stop() error {
-	s.stream.Stop()
-	for _, c := range s.casts {
-		if closer, ok := c.(io.Closer); ok {
-			closer.Close()
-		}
-	}
-	return nil
-}
-
-func (s *Component) setOn(on bool) {
-	if on {
-		opts, err := s.stream.On(s.settings)
-		if err != nil {
-			log.Printf("[%s] Turn on camera err: %v", s.ref.ComponentID(), err)
-			return
-		}
-		s.stateDp.Update(&State{
-			On:     true,
-			Width:  opts.Width,
-			Height: opts.Height,
-			FourCC: opts.FourCC,
-		})
-	} else {
-		if err := s.stream.Off(); err != nil {
-			log.Printf("[%s] Turn off camera err: %v", s.ref.ComponentID(), err)
-			return
-		}
-		s.stateDp.Update(&State{})
-	}
-}
-
-func (s *Component) setCastTo(addr string) {
-	if err := s.udpCast.SetRemoteAddr(addr); err != nil {
-		log.Printf("[%s] SetRemoteAddr(%s) err: %v", s.ref.ComponentID(), addr, err)
-		return
-	}
-	s.recvDp.Update(addr)
+	return s.caster.Stop()
 }
 
 // Type is the Component type
@@ -194,4 +92,8 @@ var Type = eng.DefineComponentType("v4l2.camera",
 		return NewComponent(ref)
 	})).
 	Describe("[V4L2] Camera").
+	Reactor("on", false).
+	Reactor("cast", "").
+	DataPoint("state", State{}).
+	DataPoint("receiver", "").
 	Register()
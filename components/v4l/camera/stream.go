@@ -2,32 +2,20 @@ package camera
 
 import (
 	"bytes"
-	"encoding/binary"
 	"fmt"
 	"image"
 	"image/jpeg"
-	"log"
-	"time"
+	"sync/atomic"
 
 	"github.com/blackjack/webcam"
-	cmn "github.com/robotalks/talk/core/common"
+	"github.com/robotalks/talk/components/v4l/stream"
 )
 
-// Options is camera options
-type Options struct {
-	Device  string
-	Width   int
-	Height  int
-	FourCC  FourCC
-	Quality *int
-	WithSeq bool
-	SeqSrc  string
-}
-
-// Camera is camera device
+// Camera is camera device, it implements stream.Source
 type Camera struct {
 	Options
-	cam *webcam.Webcam
+	cam    *webcam.Webcam
+	closed int32
 }
 
 // Open opens the camera device
@@ -64,9 +52,15 @@ func (s *Camera) Open() error {
 
 // Close closes the camera
 func (s *Camera) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
 	return s.cam.Close()
 }
 
+// Closed determines if the camera is closed by Close
+func (s *Camera) Closed() bool {
+	return atomic.LoadInt32(&s.closed) != 0
+}
+
 // GetFrame reads one frame
 func (s *Camera) GetFrame() ([]byte, error) {
 	err := s.cam.WaitForFrame(1)
@@ -81,7 +75,6 @@ func (s *Camera) GetFrame() ([]byte, error) {
 	if frame == nil {
 		return nil, nil
 	}
-
 	if s.FourCC == FourCCYUYV {
 		// need jpeg encoding
 		m := image.NewYCbCr(image.Rect(0, 0, s.Width, s.Height),
@@ -106,121 +99,36 @@ func (s *Camera) GetFrame() ([]byte, error) {
 		frame = jpg.Bytes()
 	}
 
-	l := len(frame)
-	if s.WithSeq && len(frame) > 4 && frame[l-2] == 0xff && frame[l-1] == 0xd9 {
-		comment := fmt.Sprintf("id:%d@%s", time.Now().Unix(), s.SeqSrc)
-		var buf bytes.Buffer
-		buf.Write([]byte{0xff, 0xfe})
-		commentLen := uint16(len(comment))
-		binary.Write(&buf, binary.BigEndian, commentLen)
-		buf.WriteString(comment)
-		buf.Write([]byte{0xff, 0xd9})
-		frame = append(frame[:l-2], buf.Bytes()...)
+	if s.WithSeq {
+		frame = stream.AddSeqComment(frame, s.SeqSrc)
 	}
 	return frame, nil
 }
 
-// Stream is camera streamer
-type Stream struct {
-	Casts []cmn.CastTarget
-
-	cam     *Camera
-	frameCh chan []byte
-	opCh    chan func()
-	stopCh  chan struct{}
-}
-
-// Start starts the background streamer
-func (s *Stream) Start() {
-	s.frameCh = make(chan []byte, 1)
-	s.opCh = make(chan func())
-	s.stopCh = make(chan struct{})
-	go s.run(s.frameCh, s.opCh, s.stopCh)
-}
-
-// Stop stops the background streamer
-func (s *Stream) Stop() {
-	s.Off()
-	if ch := s.opCh; ch != nil {
-		s.opCh = nil
-		close(ch)
-		<-s.stopCh
+// OpenCamera opens the camera device as the stream.Source
+func OpenCamera(settings Options) (stream.Source, Options, error) {
+	cam := &Camera{Options: settings}
+	if err := cam.Open(); err != nil {
+		return nil, settings, err
 	}
+	return cam, cam.Options, nil
 }
 
-// On turns on camera
-func (s *Stream) On(settings Options) (opts Options, err error) {
-	err = s.Do(func() error {
-		if cam := s.cam; cam == nil {
-			cam = &Camera{Options: settings}
-			if err = cam.Open(); err != nil {
-				return err
-			}
-			s.cam = cam
-			opts = cam.Options
-			go s.stream(cam)
-		}
-		return nil
-	})
-	return
-}
-
-// Off turns off camera
-func (s *Stream) Off() error {
-	return s.Do(func() error {
-		if cam := s.cam; cam != nil {
-			s.cam = nil
-			cam.Close()
-		}
-		return nil
-	})
-}
-
-// Do runs an operation in stream task
-func (s *Stream) Do(fn func() error) error {
-	if ch := s.opCh; ch != nil {
-		errCh := make(chan error)
-		ch <- func() {
-			errCh <- fn()
-		}
-		return <-errCh
-	}
-	return nil
-}
+// Aliases of the types moved to package stream
+type (
+	Options = stream.Options
+	Stream  = stream.Stream
+	State   = stream.State
+	FourCC  = stream.FourCC
+)
 
-func (s *Stream) run(frameCh <-chan []byte, opCh <-chan func(), stopCh chan struct{}) {
-	defer func() {
-		if cam := s.cam; cam != nil {
-			s.cam = nil
-			cam.Close()
-		}
-		close(stopCh)
-	}()
-	for {
-		select {
-		case frame := <-frameCh:
-			for _, c := range s.Casts {
-				c.Cast(frame)
-			}
-		case op, ok := <-opCh:
-			if !ok {
-				return
-			}
-			op()
-		}
-	}
-}
+// FourCC formats
+const (
+	FourCCMJPG = stream.FourCCMJPG
+	FourCCYUYV = stream.FourCCYUYV
+)
 
-func (s *Stream) stream(cam *Camera) {
-	for {
-		frame, err := cam.GetFrame()
-		if err != nil {
-			log.Printf("Camera Stream STOP: %v", err)
-			cam.Close()
-			break
-		}
-		if frame != nil {
-			s.frameCh <- frame
-		}
-	}
+// ParseFourCC parses FourCC from a string
+func ParseFourCC(str string) (FourCC, error) {
+	return stream.ParseFourCC(str)
 }
@@ -3,4 +3,5 @@ package v4l
 import (
 	// import all components
 	_ "github.com/robotalks/talk/components/v4l/camera"
+	_ "github.com/robotalks/talk/components/v4l/virtual"
 )
@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/denisbrodbeck/machineid"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	cmn "github.com/robotalks/talk/core/common"
+	eng "github.com/robotalks/talk/core/engine"
+)
+
+// Config defines the configuration of streaming frames
+type Config struct {
+	Width   int               `map:"width"`
+	Height  int               `map:"height"`
+	Quality *int              `map:"quality"`
+	AutoOn  bool              `map:"auto-on"`
+	WithSeq bool              `map:"with-seq"`
+	SeqSrc  string            `map:"seq-source"`
+	Casts   map[string]string `map:"cast"`
+}
+
+// State defines camera state
+type State struct {
+	On     bool   `json:"on"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	FourCC FourCC `json:"fourcc,omitempty"`
+}
+
+// Caster is shared by camera components to stream frames to
+// the casts with the on and cast reactors
+type Caster struct {
+	comp     v0.Component
+	conf     *Config
+	settings Options
+	stateDp  *mqhub.DataPoint
+	recvDp   *mqhub.DataPoint
+	imageDp  *mqhub.DataPoint
+	onOff    *mqhub.Reactor
+	castTo   *mqhub.Reactor
+	udpCast  *cmn.UDPCast
+	casts    []cmn.CastTarget
+	stream   *Stream
+}
+
+// NewCaster creates a Caster streaming frames from the sources opened by open
+func NewCaster(comp v0.Component, conf *Config, settings Options, open SourceOpener) (*Caster, error) {
+	s := &Caster{
+		comp:     comp,
+		conf:     conf,
+		settings: settings,
+		stateDp:  &mqhub.DataPoint{Name: "state", Retain: true},
+		recvDp:   &mqhub.DataPoint{Name: "receiver", Retain: true},
+	}
+
+	if conf.WithSeq && conf.SeqSrc == "" {
+		var err error
+		if conf.SeqSrc, err = machineid.ID(); err != nil {
+			return nil, fmt.Errorf("fail to get machine-id: %v", err)
+		}
+	}
+
+	s.onOff = mqhub.ReactorAs("on", s.setOn)
+	s.castTo = mqhub.ReactorAs("cast", s.setCastTo)
+
+	s.settings.Width, s.settings.Height = conf.Width, conf.Height
+	s.settings.Quality = conf.Quality
+	s.settings.WithSeq = conf.WithSeq
+	s.settings.SeqSrc = conf.SeqSrc
+
+	s.udpCast = &cmn.UDPCast{}
+	s.casts = []cmn.CastTarget{s.udpCast}
+
+	for t, val := range conf.Casts {
+		switch t {
+		case "udp":
+			s.casts = append(s.casts, &cmn.UDPCast{Address: val})
+		case "endpoint":
+			s.imageDp = &mqhub.DataPoint{Name: val}
+			s.casts = append(s.casts, &cmn.DataPointCast{DP: s.imageDp})
+		default:
+			return nil, fmt.Errorf("unknown cast type %s", t)
+		}
+	}
+
+	s.stream = &Stream{
+		Casts:  s.casts,
+		Open:   open,
+		Logger: v0.LoggerOf(comp.Ref()),
+		OnFailure: func(err error) {
+			eng.ReportExit(comp, err)
+		},
+	}
+	return s, nil
+}
+
+// RegisterMetrics implements v0.Instrumented
+func (s *Caster) RegisterMetrics(m v0.Metrics) {
+	s.stream.Captured = m.Counter("camera_frames_captured_total", "Frames captured from the camera")
+	sent := m.Counter("camera_cast_udp_bytes_total", "Bytes of frames casted via UDP")
+	for _, c := range s.casts {
+		if udpCast, ok := c.(*cmn.UDPCast); ok {
+			udpCast.SentBytes = sent
+		}
+	}
+}
+
+// Endpoints implements v0.Stateful
+func (s *Caster) Endpoints() (endpoints []mqhub.Endpoint) {
+	endpoints = []mqhub.Endpoint{s.onOff, s.castTo, s.stateDp, s.recvDp}
+	if s.imageDp != nil {
+		endpoints = append(endpoints, s.imageDp)
+	}
+	return
+}
+
+// Start implements v0.LifecycleCtl
+func (s *Caster) Start() error {
+	for _, c := range s.casts {
+		if udpCast, ok := c.(*cmn.UDPCast); ok {
+			if err := udpCast.Dial(); err != nil {
+				return fmt.Errorf("start UDP cast error: %v", err)
+			}
+		}
+	}
+	s.stream.Start()
+	s.stateDp.Update(&State{})
+	s.recvDp.Update("")
+	v0.LoggerOf(s.comp.Ref()).Info("started", "auto-on", s.conf.AutoOn)
+	if s.conf.AutoOn {
+		s.setOn(true)
+	}
+	return nil
+}
+
+// Stop implements v0.LifecycleCtl
+func (s *Caster) Stop() error {
+	s.stream.Stop()
+	for _, c := range s.casts {
+		if closer, ok := c.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	return nil
+}
+
+func (s *Caster) setOn(on bool) {
+	if on {
+		opts, err := s.stream.On(s.settings)
+		if err != nil {
+			v0.LoggerOf(s.comp.Ref()).Error("turn on camera failed", "err", err)
+			return
+		}
+		s.stateDp.Update(&State{
+			On:     true,
+			Width:  opts.Width,
+			Height: opts.Height,
+			FourCC: opts.FourCC,
+		})
+	} else {
+		if err := s.stream.Off(); err != nil {
+			v0.LoggerOf(s.comp.Ref()).Error("turn off camera failed", "err", err)
+			return
+		}
+		s.stateDp.Update(&State{})
+	}
+}
+
+func (s *Caster) setCastTo(addr string) {
+	if err := s.udpCast.SetRemoteAddr(addr); err != nil {
+		v0.LoggerOf(s.comp.Ref()).Error("set cast address failed", "addr", addr, "err", err)
+		return
+	}
+	s.recvDp.Update(addr)
+}
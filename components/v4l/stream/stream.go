@@ -0,0 +1,178 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/robotalks/talk/contract/v0"
+	cmn "github.com/robotalks/talk/core/common"
+)
+
+// Options is camera options
+type Options struct {
+	Device  string
+	Width   int
+	Height  int
+	FourCC  FourCC
+	Quality *int
+	WithSeq bool
+	SeqSrc  string
+}
+
+// AddSeqComment inserts the sequence comment before the end of JPEG frame
+func AddSeqComment(frame []byte, seqSrc string) []byte {
+	l := len(frame)
+	if l > 4 && frame[l-2] == 0xff && frame[l-1] == 0xd9 {
+		comment := fmt.Sprintf("id:%d@%s", time.Now().Unix(), seqSrc)
+		var buf bytes.Buffer
+		buf.Write([]byte{0xff, 0xfe})
+		commentLen := uint16(len(comment))
+		binary.Write(&buf, binary.BigEndian, commentLen)
+		buf.WriteString(comment)
+		buf.Write([]byte{0xff, 0xd9})
+		frame = append(frame[:l-2], buf.Bytes()...)
+	}
+	return frame
+}
+
+// Source produces frames for Stream
+type Source interface {
+	// GetFrame reads one frame, a nil frame without error is skipped
+	GetFrame() ([]byte, error)
+	// Close closes the source
+	Close() error
+	// Closed determines if the source is closed by Close
+	Closed() bool
+}
+
+// SourceOpener opens a Source and returns the actual options
+type SourceOpener func(Options) (Source, Options, error)
+
+// Stream is camera streamer
+type Stream struct {
+	Casts []cmn.CastTarget
+	// Open opens the frame source
+	Open SourceOpener
+	// OnFailure is called when streaming stops unexpectedly
+	OnFailure func(error)
+	// Logger is optional to log the failures
+	Logger v0.Logger
+	// Captured optionally counts the frames
+	Captured v0.Counter
+
+	cam     Source
+	frameCh chan []byte
+	opCh    chan func()
+	stopCh  chan struct{}
+}
+
+// Start starts the background streamer
+func (s *Stream) Start() {
+	s.frameCh = make(chan []byte, 1)
+	s.opCh = make(chan func())
+	s.stopCh = make(chan struct{})
+	go s.run(s.frameCh, s.opCh, s.stopCh)
+}
+
+// Stop stops the background streamer
+func (s *Stream) Stop() {
+	s.Off()
+	if ch := s.opCh; ch != nil {
+		s.opCh = nil
+		close(ch)
+		<-s.stopCh
+	}
+}
+
+// On turns on camera
+func (s *Stream) On(settings Options) (opts Options, err error) {
+	err = s.Do(func() error {
+		if s.cam == nil {
+			if s.Open == nil {
+				return fmt.Errorf("no frame source")
+			}
+			var cam Source
+			if cam, opts, err = s.Open(settings); err != nil {
+				return err
+			}
+			s.cam = cam
+			go s.stream(cam)
+		}
+		return nil
+	})
+	return
+}
+
+// Off turns off camera
+func (s *Stream) Off() error {
+	return s.Do(func() error {
+		if cam := s.cam; cam != nil {
+			s.cam = nil
+			cam.Close()
+		}
+		return nil
+	})
+}
+
+// Do runs an operation in stream task
+func (s *Stream) Do(fn func() error) error {
+	if ch := s.opCh; ch != nil {
+		errCh := make(chan error)
+		ch <- func() {
+			errCh <- fn()
+		}
+		return <-errCh
+	}
+	return nil
+}
+
+func (s *Stream) run(frameCh <-chan []byte, opCh <-chan func(), stopCh chan struct{}) {
+	defer func() {
+		if cam := s.cam; cam != nil {
+			s.cam = nil
+			cam.Close()
+		}
+		close(stopCh)
+	}()
+	for {
+		select {
+		case frame := <-frameCh:
+			for _, c := range s.Casts {
+				c.Cast(frame)
+			}
+		case op, ok := <-opCh:
+			if !ok {
+				return
+			}
+			op()
+		}
+	}
+}
+
+func (s *Stream) stream(cam Source) {
+	for {
+		frame, err := cam.GetFrame()
+		if err != nil {
+			if cam.Closed() {
+				break
+			}
+			if s.Logger != nil {
+				s.Logger.Error("camera stream stopped", "err", err)
+			}
+			cam.Close()
+			if s.OnFailure != nil {
+				s.OnFailure(err)
+			}
+			break
+		}
+		if frame == nil {
+			continue
+		}
+		if s.Captured != nil {
+			s.Captured.Inc()
+		}
+		s.frameCh <- frame
+	}
+}
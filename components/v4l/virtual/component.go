@@ -0,0 +1,144 @@
+package virtual
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/components/v4l/stream"
+	"github.com/robotalks/talk/contract/v0"
+	eng "github.com/robotalks/talk/core/engine"
+)
+
+// Config defines virtual camera configuration,
+// frames are read from the JPEG files in Dir, the MJPEG File,
+// or generated as the test pattern if neither is specified,
+// relative paths are from the directory of the spec file
+type Config struct {
+	Dir       string        `map:"dir"`
+	File      string        `map:"file"`
+	Loop      bool          `map:"loop"`
+	FrameRate eng.Frequency `map:"frame-rate"`
+	stream.Config
+}
+
+// Component is a camera without device
+type Component struct {
+	Config
+
+	ref    v0.ComponentRef
+	caster *stream.Caster
+}
+
+// NewComponent creates a Component
+func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
+	s := &Component{
+		Config: Config{
+			Loop:      true,
+			FrameRate: 15,
+			Config: stream.Config{
+				Width:  640,
+				Height: 480,
+			},
+		},
+		ref: ref,
+	}
+	err := eng.SetupComponent(s, ref)
+	if err != nil {
+		return nil, err
+	}
+	if s.Dir != "" && s.File != "" {
+		return nil, fmt.Errorf("only one of dir and file can be specified")
+	}
+	if s.Width <= 0 || s.Height <= 0 {
+		return nil, fmt.Errorf("invalid size %dx%d", s.Width, s.Height)
+	}
+	s.caster, err = stream.NewCaster(s, &s.Config.Config, stream.Options{FourCC: stream.FourCCMJPG}, s.open)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RegisterMetrics implements v0.Instrumented
+func (s *Component) RegisterMetrics(m v0.Metrics) {
+	s.caster.RegisterMetrics(m)
+}
+
+// Ref implements v0.Component
+func (s *Component) Ref() v0.ComponentRef {
+	return s.ref
+}
+
+// Type implements v0.Component
+func (s *Component) Type() v0.ComponentType {
+	return Type
+}
+
+// Endpoints implements v0.Stateful
+func (s *Component) Endpoints() []mqhub.Endpoint {
+	return s.caster.Endpoints()
+}
+
+// Start implements v0.LifecycleCtl
+func (s *Component) Start() error {
+	return s.caster.Start()
+}
+
+// Stop implements v0.LifecycleCtl
+func (s *Component) Stop() error {
+	return s.caster.Stop()
+}
+
+func (s *Component) open(settings stream.Options) (stream.Source, stream.Options, error) {
+	var reader FrameReader
+	switch {
+	case s.Dir != "":
+		dirReader, err := NewDirReader(eng.FilePath(s.ref, "dir", s.Dir))
+		if err != nil {
+			return nil, settings, err
+		}
+		reader = dirReader
+	case s.File != "":
+		mjpegReader, err := OpenMJPEG(eng.FilePath(s.ref, "file", s.File))
+		if err != nil {
+			return nil, settings, err
+		}
+		reader = mjpegReader
+	default:
+		reader = &PatternReader{
+			Width:   settings.Width,
+			Height:  settings.Height,
+			Quality: settings.Quality,
+		}
+	}
+	if s.Dir != "" || s.File != "" {
+		// the size of recorded frames is from the first frame
+		frame, err := reader.ReadFrame()
+		if err == nil {
+			err = reader.Rewind()
+		}
+		if err != nil {
+			reader.Close()
+			return nil, settings, err
+		}
+		if conf, err := jpeg.DecodeConfig(bytes.NewReader(frame)); err == nil {
+			settings.Width, settings.Height = conf.Width, conf.Height
+		}
+	}
+	return NewSource(settings, reader, s.Loop, s.FrameRate), settings, nil
+}
+
+// Type is the Component type of virtual camera
+var Type = eng.DefineComponentType("v4l2.camera.virtual",
+	eng.ComponentFactoryFunc(func(ref v0.ComponentRef) (v0.Component, error) {
+		return NewComponent(ref)
+	})).
+	Describe("[V4L2] Virtual Camera from JPEG files, MJPEG file or test pattern").
+	Prototype(&Component{}).
+	Reactor("on", false).
+	Reactor("cast", "").
+	DataPoint("state", stream.State{}).
+	DataPoint("receiver", "").
+	Register()
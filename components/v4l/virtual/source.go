@@ -0,0 +1,418 @@
+package virtual
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robotalks/talk/components/v4l/stream"
+	eng "github.com/robotalks/talk/core/engine"
+)
+
+// ErrSourceClosed is returned when reading from a closed Source
+var ErrSourceClosed = fmt.Errorf("source closed")
+
+// FrameReader reads JPEG frames for Source
+type FrameReader interface {
+	// ReadFrame reads the next frame, io.EOF at the end
+	ReadFrame() ([]byte, error)
+	// Rewind restarts from the first frame
+	Rewind() error
+	// Close releases the resources
+	Close() error
+}
+
+// Source reads frames from a FrameReader at the FrameRate,
+// frames are read without waiting if FrameRate is zero,
+// it implements stream.Source
+type Source struct {
+	stream.Options
+	// Reader provides the frames
+	Reader FrameReader
+	// Loop rewinds the reader at the end, otherwise the source is closed
+	Loop bool
+	// FrameRate paces the frames
+	FrameRate eng.Frequency
+
+	lock   sync.Mutex
+	next   time.Time
+	doneCh chan struct{}
+	closed bool
+}
+
+// NewSource creates a Source
+func NewSource(opts stream.Options, reader FrameReader, loop bool, frameRate eng.Frequency) *Source {
+	return &Source{
+		Options:   opts,
+		Reader:    reader,
+		Loop:      loop,
+		FrameRate: frameRate,
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// GetFrame implements stream.Source
+func (s *Source) GetFrame() ([]byte, error) {
+	if err := s.wait(); err != nil {
+		return nil, err
+	}
+	frame, err := s.Reader.ReadFrame()
+	if err == io.EOF && s.Loop {
+		if err = s.Reader.Rewind(); err == nil {
+			frame, err = s.Reader.ReadFrame()
+		}
+	}
+	if err == io.EOF {
+		// stop streaming quietly at the end
+		s.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.WithSeq {
+		frame = stream.AddSeqComment(frame, s.SeqSrc)
+	}
+	return frame, nil
+}
+
+// Close implements stream.Source
+func (s *Source) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.doneCh)
+	return s.Reader.Close()
+}
+
+// Closed implements stream.Source
+func (s *Source) Closed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+func (s *Source) wait() error {
+	if s.FrameRate <= 0 {
+		if s.Closed() {
+			return ErrSourceClosed
+		}
+		return nil
+	}
+	now := time.Now()
+	if s.next.Before(now) {
+		s.next = now
+	}
+	timer := time.NewTimer(s.next.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		s.next = s.next.Add(s.FrameRate.Period())
+		return nil
+	case <-s.doneCh:
+		return ErrSourceClosed
+	}
+}
+
+// DirReader reads JPEG files in a directory in the order of file names
+type DirReader struct {
+	Files []string
+
+	index int
+}
+
+// NewDirReader creates a DirReader from the .jpg and .jpeg files in dir
+func NewDirReader(dir string) (*DirReader, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &DirReader{}
+	for _, info := range infos {
+		ext := strings.ToLower(filepath.Ext(info.Name()))
+		if !info.IsDir() && (ext == ".jpg" || ext == ".jpeg") {
+			r.Files = append(r.Files, filepath.Join(dir, info.Name()))
+		}
+	}
+	if len(r.Files) == 0 {
+		return nil, fmt.Errorf("no JPEG files in %s", dir)
+	}
+	sort.Strings(r.Files)
+	return r, nil
+}
+
+// ReadFrame implements FrameReader
+func (r *DirReader) ReadFrame() ([]byte, error) {
+	if r.index >= len(r.Files) {
+		return nil, io.EOF
+	}
+	frame, err := ioutil.ReadFile(r.Files[r.index])
+	r.index++
+	return frame, err
+}
+
+// Rewind implements FrameReader
+func (r *DirReader) Rewind() error {
+	r.index = 0
+	return nil
+}
+
+// Close implements FrameReader
+func (r *DirReader) Close() error {
+	return nil
+}
+
+// MJPEGReader reads concatenated JPEG frames from an MJPEG stream,
+// bytes between frames (e.g. multipart headers) are skipped
+type MJPEGReader struct {
+	file   io.ReadSeeker
+	reader *bufio.Reader
+}
+
+// NewMJPEGReader creates an MJPEGReader
+func NewMJPEGReader(file io.ReadSeeker) *MJPEGReader {
+	return &MJPEGReader{file: file, reader: bufio.NewReader(file)}
+}
+
+// OpenMJPEG opens an MJPEG file
+func OpenMJPEG(fn string) (*MJPEGReader, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	return NewMJPEGReader(f), nil
+}
+
+// ReadFrame implements FrameReader
+func (r *MJPEGReader) ReadFrame() ([]byte, error) {
+	// find SOI
+	var prev byte
+	for {
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if prev == 0xff && b == 0xd8 {
+			break
+		}
+		prev = b
+	}
+	frame := []byte{0xff, 0xd8}
+	// read segments until SOS
+	for {
+		marker, err := r.readMarker()
+		if err != nil {
+			return nil, r.corrupted(err)
+		}
+		frame = append(frame, 0xff, marker)
+		if marker == 0xd9 {
+			return frame, nil
+		}
+		if marker == 0x01 || marker >= 0xd0 && marker <= 0xd7 {
+			continue
+		}
+		var size [2]byte
+		if _, err = io.ReadFull(r.reader, size[:]); err != nil {
+			return nil, r.corrupted(err)
+		}
+		l := int(size[0])<<8 | int(size[1])
+		if l < 2 {
+			return nil, fmt.Errorf("invalid JPEG segment length %d", l)
+		}
+		seg := make([]byte, l-2)
+		if _, err = io.ReadFull(r.reader, seg); err != nil {
+			return nil, r.corrupted(err)
+		}
+		frame = append(frame, size[:]...)
+		frame = append(frame, seg...)
+		if marker == 0xda {
+			break
+		}
+	}
+	// entropy coded data until EOI
+	for {
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			return nil, r.corrupted(err)
+		}
+		frame = append(frame, b)
+		if b != 0xff {
+			continue
+		}
+		if b, err = r.reader.ReadByte(); err != nil {
+			return nil, r.corrupted(err)
+		}
+		frame = append(frame, b)
+		if b == 0xd9 {
+			return frame, nil
+		}
+	}
+}
+
+// Rewind implements FrameReader
+func (r *MJPEGReader) Rewind() error {
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r.reader.Reset(r.file)
+	return nil
+}
+
+// Close implements FrameReader
+func (r *MJPEGReader) Close() error {
+	if closer, ok := r.file.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *MJPEGReader) readMarker() (byte, error) {
+	b, err := r.reader.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if b != 0xff {
+		return 0, fmt.Errorf("invalid JPEG marker %02x", b)
+	}
+	// skip fill bytes
+	for b == 0xff {
+		if b, err = r.reader.ReadByte(); err != nil {
+			return 0, err
+		}
+	}
+	return b, nil
+}
+
+func (r *MJPEGReader) corrupted(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// PatternReader generates frames of color bars with a moving box
+// and the timestamp overlay
+type PatternReader struct {
+	Width   int
+	Height  int
+	Quality *int
+
+	seq int
+}
+
+// ReadFrame implements FrameReader
+func (r *PatternReader) ReadFrame() ([]byte, error) {
+	m := image.NewRGBA(image.Rect(0, 0, r.Width, r.Height))
+	for n, c := range patternBars {
+		x0, x1 := r.Width*n/len(patternBars), r.Width*(n+1)/len(patternBars)
+		draw.Draw(m, image.Rect(x0, 0, x1, r.Height), image.NewUniform(c), image.ZP, draw.Src)
+	}
+
+	size := r.Height / 4
+	if size < 8 {
+		size = 8
+	}
+	travel := r.Width - size
+	if travel <= 0 {
+		travel = 1
+	}
+	x := r.seq * 4 % (travel * 2)
+	if x >= travel {
+		x = travel*2 - x
+	}
+	y := (r.Height - size) / 2
+	box := patternBars[(r.seq/30)%len(patternBars)]
+	box = color.RGBA{R: 255 - box.R, G: 255 - box.G, B: 255 - box.B, A: 255}
+	draw.Draw(m, image.Rect(x, y, x+size, y+size), image.NewUniform(box), image.ZP, draw.Src)
+
+	text := fmt.Sprintf("%s %d", time.Now().Format("15:04:05.000"), r.seq)
+	scale := r.Width / 160
+	if scale < 1 {
+		scale = 1
+	}
+	drawText(m, text, 4*scale, r.Height-(glyphHeight+2)*scale, scale)
+	r.seq++
+
+	var jpg bytes.Buffer
+	var opt *jpeg.Options
+	if q := r.Quality; q != nil {
+		opt = &jpeg.Options{Quality: *q}
+	}
+	if err := jpeg.Encode(&jpg, m, opt); err != nil {
+		return nil, err
+	}
+	return jpg.Bytes(), nil
+}
+
+// Rewind implements FrameReader
+func (r *PatternReader) Rewind() error {
+	r.seq = 0
+	return nil
+}
+
+// Close implements FrameReader
+func (r *PatternReader) Close() error {
+	return nil
+}
+
+var patternBars = []color.RGBA{
+	{R: 192, G: 192, B: 192, A: 255},
+	{R: 192, G: 192, B: 0, A: 255},
+	{R: 0, G: 192, B: 192, A: 255},
+	{R: 0, G: 192, B: 0, A: 255},
+	{R: 192, G: 0, B: 192, A: 255},
+	{R: 192, G: 0, B: 0, A: 255},
+	{R: 0, G: 0, B: 192, A: 255},
+}
+
+const glyphWidth, glyphHeight = 3, 5
+
+// glyphs are 3x5 bitmaps, each row is 3 bits from left to right
+var glyphs = map[rune][glyphHeight]byte{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	':': {0, 2, 0, 2, 0},
+	'.': {0, 0, 0, 0, 2},
+}
+
+func drawText(m draw.Image, text string, x, y, scale int) {
+	bounds := image.Rect(x-scale, y-scale,
+		x+len(text)*(glyphWidth+1)*scale, y+(glyphHeight+1)*scale)
+	draw.Draw(m, bounds, image.Black, image.ZP, draw.Src)
+	for _, ch := range text {
+		if glyph, ok := glyphs[ch]; ok {
+			for row, bits := range glyph {
+				for col := 0; col < glyphWidth; col++ {
+					if bits&(4>>uint(col)) == 0 {
+						continue
+					}
+					px, py := x+col*scale, y+row*scale
+					draw.Draw(m, image.Rect(px, py, px+scale, py+scale), image.White, image.ZP, draw.Src)
+				}
+			}
+		}
+		x += (glyphWidth + 1) * scale
+	}
+}
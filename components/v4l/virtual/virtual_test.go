@@ -0,0 +1,177 @@
+package virtual
+
+import (
+	"bytes"
+	"image/jpeg"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/components/v4l/stream"
+	eng "github.com/robotalks/talk/core/engine"
+	"github.com/robotalks/talk/core/enginetest"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+func testFrames(t *testing.T, n int) (frames [][]byte) {
+	r := &PatternReader{Width: 64, Height: 48}
+	for i := 0; i < n; i++ {
+		frame, err := r.ReadFrame()
+		assert.NoError(t, err)
+		frames = append(frames, frame)
+	}
+	return
+}
+
+func TestPatternReader(t *testing.T) {
+	frames := testFrames(t, 2)
+	m, err := jpeg.Decode(bytes.NewReader(frames[0]))
+	assert.NoError(t, err)
+	assert.Equal(t, 64, m.Bounds().Dx())
+	assert.Equal(t, 48, m.Bounds().Dy())
+	assert.NotEqual(t, frames[0], frames[1])
+}
+
+func TestMJPEGReader(t *testing.T) {
+	frames := testFrames(t, 2)
+	// an APP1 segment containing EOI and SOI markers
+	frames[0] = append([]byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x06, 0xff, 0xd9, 0xff, 0xd8}, frames[0][2:]...)
+	var buf bytes.Buffer
+	buf.WriteString("--frame\r\nContent-Type: image/jpeg\r\n\r\n")
+	buf.Write(frames[0])
+	buf.WriteString("\r\n--frame\r\nContent-Type: image/jpeg\r\n\r\n")
+	buf.Write(frames[1])
+
+	r := NewMJPEGReader(bytes.NewReader(buf.Bytes()))
+	for _, expected := range frames {
+		frame, err := r.ReadFrame()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, frame)
+	}
+	_, err := r.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, r.Rewind())
+	frame, err := r.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, frames[0], frame)
+
+	r = NewMJPEGReader(bytes.NewReader(frames[1][:len(frames[1])/2]))
+	_, err = r.ReadFrame()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func writeTestDir(t *testing.T, frames [][]byte) string {
+	dir, err := ioutil.TempDir("", "vcam")
+	assert.NoError(t, err)
+	for n, frame := range frames {
+		fn := filepath.Join(dir, string(rune('a'+n))+".jpg")
+		assert.NoError(t, ioutil.WriteFile(fn, frame, 0644))
+	}
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("text"), 0644))
+	return dir
+}
+
+func TestVirtualSource(t *testing.T) {
+	frames := testFrames(t, 2)
+	dir := writeTestDir(t, frames)
+	defer os.RemoveAll(dir)
+
+	r, err := NewDirReader(dir)
+	assert.NoError(t, err)
+	assert.Len(t, r.Files, 2)
+	src := NewSource(stream.Options{WithSeq: true, SeqSrc: "test"}, r, false, 0)
+	for _, expected := range frames {
+		frame, err := src.GetFrame()
+		assert.NoError(t, err)
+		assert.True(t, bytes.Contains(frame, []byte("@test")))
+		assert.Equal(t, expected[:len(expected)-2], frame[:len(expected)-2])
+	}
+	_, err = src.GetFrame()
+	assert.Equal(t, io.EOF, err)
+	assert.True(t, src.Closed())
+
+	src = NewSource(stream.Options{}, &PatternReader{Width: 16, Height: 16}, true, 1)
+	_, err = src.GetFrame()
+	assert.NoError(t, err)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		src.Close()
+	}()
+	_, err = src.GetFrame()
+	assert.Equal(t, ErrSourceClosed, err)
+
+	_, err = NewDirReader(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestVirtualComponent(t *testing.T) {
+	frames := testFrames(t, 1)
+	dir := writeTestDir(t, frames)
+	defer os.RemoveAll(dir)
+
+	_, err := enginetest.NewRef("cam").Set("dir", dir).Set("file", "a.mjpeg").Create(Type)
+	assert.Error(t, err)
+
+	ref := enginetest.NewRef("cam").
+		Set("dir", dir).
+		Set("frame-rate", "100Hz").
+		Set("cast", map[string]interface{}{"endpoint": "image"})
+	comp, err := ref.Create(Type)
+	assert.NoError(t, err)
+	probe := enginetest.NewProbe(comp)
+	defer probe.Close()
+	cam := comp.(*Component)
+	assert.NoError(t, cam.Start())
+
+	assert.NoError(t, probe.Send("on", true))
+	var state stream.State
+	assert.NoError(t, probe.DataPoint("state").Last(&state))
+	assert.Equal(t, stream.State{On: true, Width: 64, Height: 48, FourCC: stream.FourCCMJPG}, state)
+	image := probe.DataPoint("image")
+	assert.NoError(t, image.WaitLen(3, time.Second))
+	var frame []byte
+	assert.NoError(t, image.Messages()[2].As(&frame))
+	assert.Equal(t, frames[0], frame)
+	_, ok := image.Messages()[0].(mqhub.StreamMessage)
+	assert.True(t, ok)
+
+	assert.NoError(t, probe.Send("on", false))
+	assert.NoError(t, probe.DataPoint("state").Last(&state))
+	assert.False(t, state.On)
+	assert.NoError(t, cam.Stop())
+	assert.Empty(t, ref.Exits())
+}
+
+func TestVirtualRelativeDir(t *testing.T) {
+	frames := testFrames(t, 1)
+	dir := writeTestDir(t, frames)
+	defer os.RemoveAll(dir)
+	specFile := filepath.Join(dir, "spec.yaml")
+	assert.NoError(t, ioutil.WriteFile(specFile, []byte(`---
+name: robot
+components:
+  cam:
+    type: v4l2.camera.virtual
+    config:
+      dir: .
+`), 0644))
+
+	spec, err := eng.LoadSpecFile(specFile)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Resolve())
+	assert.NoError(t, spec.Connect(memhub.NewHub("test").Connector()))
+	defer spec.Disconnect()
+	cam := spec.ChildSpecs["cam"].Instance.(*Component)
+	src, opts, err := cam.open(stream.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, 64, opts.Width)
+	frame, err := src.GetFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, frames[0], frame)
+	assert.NoError(t, src.Close())
+}
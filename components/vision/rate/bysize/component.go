@@ -73,4 +73,6 @@ var Type = eng.DefineComponentType("vision.rate.bysize",
 		return NewComponent(ref)
 	})).
 	Describe("[Vision] Sort Detected Object By Size").
+	Prototype(&Component{}).
+	DataPoint("objects", utils.Result{}).
 	Register()
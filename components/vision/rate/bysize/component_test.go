@@ -0,0 +1,37 @@
+package bysize
+
+import (
+	"testing"
+
+	"github.com/robotalks/talk/components/vision/utils"
+	"github.com/robotalks/talk/core/enginetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateBySize(t *testing.T) {
+	objects := &enginetest.EndpointRef{}
+	comp, err := enginetest.NewRef("rate").Inject("objects", objects).Create(Type)
+	assert.NoError(t, err)
+	probe := enginetest.NewProbe(comp)
+	defer probe.Close()
+	rater := comp.(*Component)
+	assert.NoError(t, rater.Start())
+
+	objects.Publish(&utils.Result{
+		Size: utils.Size{W: 100, H: 100},
+		Objects: []*utils.Object{
+			{Type: "cup", Range: utils.Rect{Size: utils.Size{W: 10, H: 10}}},
+			{Type: "ball", Range: utils.Rect{Size: utils.Size{W: 50, H: 20}}},
+		},
+	})
+	var res utils.Result
+	assert.NoError(t, probe.DataPoint("objects").Last(&res))
+	if assert.Len(t, res.Objects, 2) {
+		assert.Equal(t, "ball", res.Objects[0].Type)
+		assert.InDelta(t, 0.1, *res.Objects[0].Rate, 1e-6)
+		assert.InDelta(t, 0.01, *res.Objects[1].Rate, 1e-6)
+	}
+
+	assert.NoError(t, rater.Stop())
+	assert.Equal(t, 0, objects.Watchers())
+}
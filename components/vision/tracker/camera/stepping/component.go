@@ -31,9 +31,9 @@ const (
 // Component is the implementation
 type Component struct {
 	Axis      string            `map:"axis"`
-	AngleMin  *float32          `map:"angle-min"`
-	AngleMax  *float32          `map:"angle-max"`
-	AngleStep *float32          `map:"angle-step"`
+	AngleMin  *eng.Angle        `map:"angle-min"`
+	AngleMax  *eng.Angle        `map:"angle-max"`
+	AngleStep *eng.Angle        `map:"angle-step"`
 	Tolerance *float32          `map:"tolerance"`
 	Objects   mqhub.EndpointRef `inject:"objects" map:"-"`
 	Servo     mqhub.EndpointRef `inject:"servo" map:"-"`
@@ -77,21 +77,21 @@ func NewComponent(ref v0.ComponentRef) (v0.Component, error) {
 	}
 
 	if s.AngleMin != nil {
-		if s.min, err = mapAngle(*s.AngleMin); err != nil {
+		if s.min, err = mapAngle(float32(s.AngleMin.Deg())); err != nil {
 			return nil, err
 		}
 	} else {
 		s.min = -90
 	}
 	if s.AngleMax != nil {
-		if s.max, err = mapAngle(*s.AngleMax); err != nil {
+		if s.max, err = mapAngle(float32(s.AngleMax.Deg())); err != nil {
 			return nil, err
 		}
 	} else {
 		s.max = 90
 	}
 	if s.AngleStep != nil {
-		s.step = *s.AngleStep / 90
+		s.step = float32(s.AngleStep.Deg()) / 90
 	} else {
 		s.step = 1.0 / 90
 	}
@@ -184,4 +184,5 @@ var Type = eng.DefineComponentType("vision.tracker.camera.stepping",
 		return NewComponent(ref)
 	})).
 	Describe("[Vision] Track Object using Simple Tiny Stepping").
+	Prototype(&Component{}).
 	Register()
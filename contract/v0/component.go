@@ -19,6 +19,13 @@ type LifecycleCtl interface {
 	Stop() error
 }
 
+// ExitReporter is implemented by ComponentRef to let a started component
+// report it stops working by itself, e.g. the process it spawned died
+type ExitReporter interface {
+	// ReportExit reports comp exits, err is nil on normal exit
+	ReportExit(comp Component, err error)
+}
+
 // ComponentRef is a reference to the component
 type ComponentRef interface {
 	// ComponentID retrieves the ID of current component
@@ -0,0 +1,114 @@
+package v0
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// LogLevel is the severity of a log record
+type LogLevel int
+
+// Log levels
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// String implements fmt.Stringer
+func (l LogLevel) String() string {
+	if l >= LogDebug && int(l) < len(logLevelNames) {
+		return logLevelNames[l]
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLogLevel parses the name of a log level
+func ParseLogLevel(name string) (LogLevel, error) {
+	name = strings.ToLower(name)
+	if name == "warning" {
+		name = "warn"
+	}
+	for n, levelName := range logLevelNames {
+		if levelName == name {
+			return LogLevel(n), nil
+		}
+	}
+	return LogInfo, fmt.Errorf("invalid log level %q", name)
+}
+
+// Logger is a leveled and structured logger,
+// fields are key-value pairs, e.g. Info("moved", "pos", 0.5)
+type Logger interface {
+	Debug(msg string, fields ...interface{})
+	Info(msg string, fields ...interface{})
+	Warn(msg string, fields ...interface{})
+	Error(msg string, fields ...interface{})
+	// With creates a logger tagging all records with fields
+	With(fields ...interface{}) Logger
+}
+
+// LoggerProvider is optionally implemented by ComponentRef
+// to provide the logger tagged with the component path and type
+type LoggerProvider interface {
+	Logger() Logger
+}
+
+// LoggerOf retrieves the logger of ref if it implements LoggerProvider,
+// otherwise the warnings and errors are written to the standard logger
+func LoggerOf(ref ComponentRef) Logger {
+	if p, ok := ref.(LoggerProvider); ok {
+		if l := p.Logger(); l != nil {
+			return l
+		}
+	}
+	return stdLogger{}
+}
+
+// stdLogger writes the warnings and errors to the standard logger
+type stdLogger struct {
+	fields []interface{}
+}
+
+func (l stdLogger) Debug(msg string, fields ...interface{}) {}
+func (l stdLogger) Info(msg string, fields ...interface{})  {}
+
+func (l stdLogger) Warn(msg string, fields ...interface{}) {
+	l.print(LogWarn, msg, fields)
+}
+
+func (l stdLogger) Error(msg string, fields ...interface{}) {
+	l.print(LogError, msg, fields)
+}
+
+func (l stdLogger) With(fields ...interface{}) Logger {
+	return stdLogger{fields: append(append([]interface{}{}, l.fields...), fields...)}
+}
+
+func (l stdLogger) print(level LogLevel, msg string, fields []interface{}) {
+	line := strings.ToUpper(level.String()) + " " + msg
+	fields = append(append([]interface{}{}, l.fields...), fields...)
+	for n := 0; n < len(fields); n += 2 {
+		var value interface{}
+		if n+1 < len(fields) {
+			value = fields[n+1]
+		}
+		line += fmt.Sprintf(" %v=%v", fields[n], value)
+	}
+	log.Print(line)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (l LogLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (l *LogLevel) UnmarshalText(text []byte) (err error) {
+	*l, err = ParseLogLevel(string(text))
+	return
+}
@@ -0,0 +1,33 @@
+package v0
+
+// Counter is a metric which only goes up
+type Counter interface {
+	Inc()
+	Add(delta float64)
+}
+
+// Gauge is a metric which goes up and down
+type Gauge interface {
+	Set(value float64)
+	Add(delta float64)
+}
+
+// Histogram counts observed values in buckets, e.g. latencies in seconds
+type Histogram interface {
+	Observe(value float64)
+}
+
+// Metrics creates the metrics of a component, the metrics are labeled
+// with the component path and type. The same name always returns the
+// same metric of the component
+type Metrics interface {
+	Counter(name, help string) Counter
+	Gauge(name, help string) Gauge
+	Histogram(name, help string) Histogram
+}
+
+// Instrumented is implemented by components registering their own metrics,
+// RegisterMetrics is called after the component is created
+type Instrumented interface {
+	RegisterMetrics(Metrics)
+}
@@ -0,0 +1,101 @@
+package v0
+
+import "strings"
+
+// Value kinds used in ValueShape
+const (
+	KindAny    = "any"
+	KindBool   = "bool"
+	KindInt    = "int"
+	KindUint   = "uint"
+	KindFloat  = "float"
+	KindString = "string"
+	KindBytes  = "bytes"
+	KindArray  = "array"
+	KindMap    = "map"
+	KindObject = "object"
+)
+
+// ValueShape describes the shape of a config value or message payload
+type ValueShape struct {
+	Kind string `json:"kind"`
+	// Type is the name of the type when the value has its own encoding
+	Type string `json:"type,omitempty"`
+	// Elem is the element shape of array or map
+	Elem *ValueShape `json:"elem,omitempty"`
+	// Fields are the fields of object
+	Fields []*FieldSchema `json:"fields,omitempty"`
+}
+
+// FieldSchema describes a named field
+type FieldSchema struct {
+	Name     string      `json:"name"`
+	Shape    *ValueShape `json:"shape"`
+	Optional bool        `json:"optional,omitempty"`
+}
+
+// InjectSchema describes an injection slot
+type InjectSchema struct {
+	Name string `json:"name"`
+	// Type is the Go type of the field receiving injections
+	Type string `json:"type"`
+	// Hub indicates a hub endpoint is accepted
+	Hub bool `json:"hub,omitempty"`
+	// Optional indicates the injection can be omitted
+	Optional bool `json:"optional,omitempty"`
+	// Multiple indicates all injections named "name" or "name.key" are accepted
+	Multiple bool `json:"multiple,omitempty"`
+}
+
+// Endpoint directions
+const (
+	EndpointDataPoint = "datapoint"
+	EndpointReactor   = "reactor"
+)
+
+// EndpointSchema describes an endpoint published by the component
+type EndpointSchema struct {
+	Name      string      `json:"name"`
+	Direction string      `json:"direction"`
+	Payload   *ValueShape `json:"payload,omitempty"`
+}
+
+// ComponentSchema describes what a component type accepts and publishes
+type ComponentSchema struct {
+	Config    []*FieldSchema    `json:"config,omitempty"`
+	Injects   []*InjectSchema   `json:"inject,omitempty"`
+	Endpoints []*EndpointSchema `json:"endpoints,omitempty"`
+}
+
+// SchemaProvider is implemented by a ComponentType which describes itself
+type SchemaProvider interface {
+	// Schema returns the schema of components of this type
+	Schema() *ComponentSchema
+}
+
+// String returns a compact human readable form of the shape
+func (s *ValueShape) String() string {
+	if s == nil {
+		return KindAny
+	}
+	switch s.Kind {
+	case KindArray:
+		return "[]" + s.Elem.String()
+	case KindMap:
+		return "map[string]" + s.Elem.String()
+	case KindObject:
+		fields := make([]string, 0, len(s.Fields))
+		for _, f := range s.Fields {
+			name := f.Name
+			if f.Optional {
+				name += "?"
+			}
+			fields = append(fields, name+": "+f.Shape.String())
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	}
+	if s.Type != "" {
+		return s.Type
+	}
+	return s.Kind
+}
@@ -0,0 +1,227 @@
+package broker
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// DefaultMaxPacketSize limits the size of incoming packets
+const DefaultMaxPacketSize = 16 << 20
+
+// ConnectTimeout is the time waiting for CONNECT after accepting a connection
+var ConnectTimeout = 10 * time.Second
+
+// Message is an application message
+type Message struct {
+	Topic   string
+	Payload []byte
+	QoS     byte
+	Retain  bool
+}
+
+// Broker is a minimal in-process MQTT 3.1.1 broker supporting
+// retained messages, wildcard subscriptions and QoS 0/1.
+// Sessions are not persisted, every connection starts a clean session.
+type Broker struct {
+	// MaxPacketSize limits incoming packets, DefaultMaxPacketSize is used if 0
+	MaxPacketSize int
+	// QueueSize is the number of outgoing packets buffered per client,
+	// messages are dropped when the queue is full
+	QueueSize int
+	// Logger is optional to log the client activities
+	Logger v0.Logger
+
+	lock      sync.RWMutex
+	clients   map[string]*client
+	retained  map[string]*Message
+	listeners []net.Listener
+	closed    bool
+	nextID    int
+}
+
+// New creates a Broker
+func New() *Broker {
+	return &Broker{
+		clients:  make(map[string]*client),
+		retained: make(map[string]*Message),
+	}
+}
+
+// Listen listens on the TCP address and serves in background
+func (b *Broker) Listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go b.Serve(ln)
+	return ln, nil
+}
+
+// Serve accepts connections until the listener is closed
+func (b *Broker) Serve(ln net.Listener) error {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		ln.Close()
+		return fmt.Errorf("broker closed")
+	}
+	b.listeners = append(b.listeners, ln)
+	b.lock.Unlock()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go b.ServeConn(conn)
+	}
+}
+
+// ServeConn serves a single client connection until it's closed
+func (b *Broker) ServeConn(conn net.Conn) {
+	c := &client{broker: b, conn: conn, r: bufio.NewReader(conn)}
+	if err := c.connect(); err != nil {
+		b.debug("connect failed", "remote", conn.RemoteAddr().String(), "err", err)
+		conn.Close()
+		return
+	}
+	err := c.serve()
+	b.disconnected(c, err)
+}
+
+// Publish delivers the message to subscribers, and keeps it if retained
+func (b *Broker) Publish(msg *Message) {
+	b.lock.Lock()
+	if msg.Retain {
+		if len(msg.Payload) == 0 {
+			delete(b.retained, msg.Topic)
+		} else {
+			retained := *msg
+			b.retained[msg.Topic] = &retained
+		}
+	}
+	clients := make([]*client, 0, len(b.clients))
+	for _, c := range b.clients {
+		clients = append(clients, c)
+	}
+	b.lock.Unlock()
+	for _, c := range clients {
+		if qos, ok := c.subscribed(msg.Topic); ok {
+			c.deliver(msg, qos, false)
+		}
+	}
+}
+
+// Retained returns the retained message on the topic
+func (b *Broker) Retained(topic string) *Message {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	return b.retained[topic]
+}
+
+// Close stops the listeners and disconnects all clients
+func (b *Broker) Close() error {
+	b.lock.Lock()
+	b.closed = true
+	listeners, clients := b.listeners, b.clients
+	b.listeners, b.clients = nil, make(map[string]*client)
+	b.lock.Unlock()
+	var errs errors.AggregatedError
+	for _, ln := range listeners {
+		errs.Add(ln.Close())
+	}
+	for _, c := range clients {
+		c.close()
+	}
+	return errs.Aggregate()
+}
+
+// register adds the connected client and takes over the session
+// of the same client ID
+func (b *Broker) register(c *client) error {
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return fmt.Errorf("broker closed")
+	}
+	if c.id == "" {
+		b.nextID++
+		c.id = "talk-" + strconv.Itoa(b.nextID)
+	}
+	prev := b.clients[c.id]
+	b.clients[c.id] = c
+	b.lock.Unlock()
+	if prev != nil {
+		b.debug("session taken over", "client", c.id)
+		prev.close()
+	}
+	return nil
+}
+
+func (b *Broker) disconnected(c *client, err error) {
+	b.lock.Lock()
+	if b.clients[c.id] == c {
+		delete(b.clients, c.id)
+	}
+	b.lock.Unlock()
+	c.close()
+	if will := c.takeWill(); will != nil {
+		b.Publish(will)
+	}
+	if err != nil {
+		b.debug("client disconnected", "client", c.id, "err", err)
+	} else {
+		b.debug("client disconnected", "client", c.id)
+	}
+}
+
+func (b *Broker) retainedFor(filter string) []*Message {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	var msgs []*Message
+	for topic, msg := range b.retained {
+		if MatchTopic(filter, topic) {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func (b *Broker) maxPacketSize() int {
+	if b.MaxPacketSize > 0 {
+		return b.MaxPacketSize
+	}
+	return DefaultMaxPacketSize
+}
+
+func (b *Broker) queueSize() int {
+	if b.QueueSize > 0 {
+		return b.QueueSize
+	}
+	return 256
+}
+
+func (b *Broker) debug(msg string, fields ...interface{}) {
+	if b.Logger != nil {
+		b.Logger.Debug(msg, fields...)
+	}
+}
+
+// ClientURL returns the mqtt:// URL connecting to the listening address,
+// the unspecified host is replaced by the loopback address
+func ClientURL(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "mqtt://" + addr
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "mqtt://" + net.JoinHostPort(host, port)
+}
@@ -0,0 +1,218 @@
+package broker
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialBroker(t *testing.T, addr, id string, will *Message) *testClient {
+	conn, err := net.Dial("tcp", addr)
+	must(t, err)
+	c := &testClient{t: t, conn: conn, r: bufio.NewReader(conn)}
+	body := appendString(nil, "MQTT")
+	flags := byte(0x02)
+	if will != nil {
+		flags |= 0x04 | will.QoS<<3
+		if will.Retain {
+			flags |= 0x20
+		}
+	}
+	body = append(body, 4, flags)
+	body = appendUint16(body, 30)
+	body = appendString(body, id)
+	if will != nil {
+		body = appendString(body, will.Topic)
+		body = appendString(body, string(will.Payload))
+	}
+	c.write(&packet{kind: pktConnect, body: body})
+	p := c.read()
+	assert.Equal(t, pktConnAck, p.kind)
+	assert.Equal(t, []byte{0, connAccepted}, p.body)
+	return c
+}
+
+func (c *testClient) write(p *packet) {
+	_, err := c.conn.Write(p.encode())
+	must(c.t, err)
+}
+
+func (c *testClient) read() *packet {
+	c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	p, err := readPacket(c.r, 0)
+	must(c.t, err)
+	return p
+}
+
+func (c *testClient) subscribe(id uint16, filter string, qos byte) []byte {
+	body := appendString(appendUint16(nil, id), filter)
+	c.write(&packet{kind: pktSubscribe, flags: 2, body: append(body, qos)})
+	p := c.read()
+	assert.Equal(c.t, pktSubAck, p.kind)
+	assert.Equal(c.t, appendUint16(nil, id), p.body[:2])
+	return p.body[2:]
+}
+
+func (c *testClient) publish(topic, payload string, qos byte, retain bool, id uint16) {
+	c.write(publishPacket(&Message{Topic: topic, Payload: []byte(payload)}, qos, retain, id))
+	if qos > 0 {
+		p := c.read()
+		assert.Equal(c.t, pktPubAck, p.kind)
+		assert.Equal(c.t, appendUint16(nil, id), p.body)
+	}
+}
+
+func (c *testClient) receive() (*Message, uint16) {
+	p := c.read()
+	assert.Equal(c.t, pktPublish, p.kind)
+	d := &decoder{data: p.body}
+	msg := &Message{Topic: d.string(), QoS: (p.flags >> 1) & 3, Retain: p.flags&1 != 0}
+	var id uint16
+	if msg.QoS > 0 {
+		id = d.uint16()
+	}
+	must(c.t, d.err)
+	msg.Payload = d.data
+	return msg, id
+}
+
+func startBroker(t *testing.T) (*Broker, string) {
+	b := New()
+	ln, err := b.Listen("127.0.0.1:0")
+	must(t, err)
+	return b, ln.Addr().String()
+}
+
+func TestMatchTopic(t *testing.T) {
+	cases := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"robot/vision/objects", "robot/vision/objects", true},
+		{"robot/vision", "robot/vision/objects", false},
+		{"robot/+/objects", "robot/vision/objects", true},
+		{"robot/+", "robot/vision/objects", false},
+		{"robot/#", "robot/vision/objects", true},
+		{"robot/#", "robot", true},
+		{"#", "robot/vision", true},
+		{"+/+", "/vision", true},
+		{"#", "$SYS/uptime", false},
+		{"+/uptime", "$SYS/uptime", false},
+		{"$SYS/#", "$SYS/uptime", true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.match, MatchTopic(c.filter, c.topic), "%s ~ %s", c.filter, c.topic)
+	}
+	assert.True(t, validFilter("a/+/#"))
+	assert.False(t, validFilter("a/#/b"))
+	assert.False(t, validFilter("a/b+"))
+	assert.False(t, validTopic("a/+"))
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	b, addr := startBroker(t)
+	defer b.Close()
+
+	sub := dialBroker(t, addr, "sub", nil)
+	pub := dialBroker(t, addr, "pub", nil)
+	assert.Equal(t, []byte{1, 0, subFailure}, func() []byte {
+		body := appendString(appendUint16(nil, 1), "robot/+/state")
+		body = appendString(append(body, 1), "robot/#")
+		body = appendString(append(body, 0), "robot/#/bad")
+		sub.write(&packet{kind: pktSubscribe, flags: 2, body: append(body, 0)})
+		p := sub.read()
+		assert.Equal(t, pktSubAck, p.kind)
+		return p.body[2:]
+	}())
+
+	pub.publish("robot/servo/state", "90", 1, false, 7)
+	msg, id := sub.receive()
+	assert.Equal(t, "robot/servo/state", msg.Topic)
+	assert.Equal(t, "90", string(msg.Payload))
+	assert.Equal(t, byte(1), msg.QoS)
+	assert.NotZero(t, id)
+	assert.False(t, msg.Retain)
+	sub.write(ackPacket(pktPubAck, id))
+
+	pub.publish("robot/vision/objects", "[]", 0, false, 0)
+	msg, _ = sub.receive()
+	assert.Equal(t, "robot/vision/objects", msg.Topic)
+	assert.Equal(t, byte(0), msg.QoS)
+
+	sub.write(&packet{kind: pktUnsubscribe, flags: 2, body: appendString(appendUint16(nil, 2), "robot/#")})
+	assert.Equal(t, pktUnsubAck, sub.read().kind)
+	pub.publish("robot/vision/objects", "[1]", 0, false, 0)
+	pub.publish("robot/servo/state", "45", 0, false, 0)
+	msg, _ = sub.receive()
+	assert.Equal(t, "45", string(msg.Payload))
+
+	sub.write(&packet{kind: pktPingReq})
+	assert.Equal(t, pktPingResp, sub.read().kind)
+}
+
+func TestRetained(t *testing.T) {
+	b, addr := startBroker(t)
+	defer b.Close()
+
+	pub := dialBroker(t, addr, "pub", nil)
+	pub.publish("robot/servo/state", "90", 1, true, 1)
+	pub.publish("robot/led/state", "on", 1, true, 2)
+	pub.publish("robot/led/state", "", 1, true, 3)
+	assert.Nil(t, b.Retained("robot/led/state"))
+
+	sub := dialBroker(t, addr, "sub", nil)
+	assert.Equal(t, []byte{0}, sub.subscribe(1, "robot/+/state", 0))
+	msg, _ := sub.receive()
+	assert.Equal(t, "robot/servo/state", msg.Topic)
+	assert.Equal(t, "90", string(msg.Payload))
+	assert.True(t, msg.Retain)
+	assert.Equal(t, byte(0), msg.QoS)
+}
+
+func TestWillAndTakeover(t *testing.T) {
+	b, addr := startBroker(t)
+	defer b.Close()
+
+	sub := dialBroker(t, addr, "sub", nil)
+	sub.subscribe(1, "robot/status", 1)
+	dev := dialBroker(t, addr, "dev", &Message{Topic: "robot/status", Payload: []byte("offline"), QoS: 1})
+	dev.conn.Close()
+	msg, _ := sub.receive()
+	assert.Equal(t, "offline", string(msg.Payload))
+	assert.Equal(t, byte(1), msg.QoS)
+
+	dialBroker(t, addr, "dev", &Message{Topic: "robot/status", Payload: []byte("lost")})
+	dialBroker(t, addr, "dev", nil)
+	msg, _ = sub.receive()
+	assert.Equal(t, "lost", string(msg.Payload))
+
+	dev = dialBroker(t, addr, "dev2", &Message{Topic: "robot/status", Payload: []byte("gone")})
+	dev.write(&packet{kind: pktDisconnect})
+	dev.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := dev.r.ReadByte()
+	assert.Error(t, err)
+	sub.publish("robot/status", "self", 0, false, 0)
+	msg, _ = sub.receive()
+	assert.Equal(t, "self", string(msg.Payload))
+}
+
+func TestClientURL(t *testing.T) {
+	assert.Equal(t, "mqtt://127.0.0.1:1883", ClientURL(":1883"))
+	assert.Equal(t, "mqtt://127.0.0.1:1883", ClientURL("0.0.0.0:1883"))
+	assert.Equal(t, "mqtt://192.168.1.2:1883", ClientURL("192.168.1.2:1883"))
+}
+
+func must(t *testing.T, err error) {
+	if err != nil {
+		t.Fatal(err)
+	}
+}
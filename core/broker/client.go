@@ -0,0 +1,296 @@
+package broker
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// client is a connected MQTT client
+type client struct {
+	broker    *Broker
+	conn      net.Conn
+	r         *bufio.Reader
+	id        string
+	keepAlive time.Duration
+	out       chan *packet
+	done      chan struct{}
+
+	lock     sync.Mutex
+	subs     map[string]byte
+	will     *Message
+	packetID uint16
+	closed   bool
+}
+
+// connect handles the CONNECT packet and registers the client
+func (c *client) connect() error {
+	c.conn.SetReadDeadline(time.Now().Add(ConnectTimeout))
+	p, err := readPacket(c.r, c.broker.maxPacketSize())
+	if err != nil {
+		return err
+	}
+	if p.kind != pktConnect {
+		return fmt.Errorf("expect CONNECT, got packet type %d", p.kind)
+	}
+	d := &decoder{data: p.body}
+	protocol, level, flags := d.string(), d.byte(), d.byte()
+	keepAlive, id := d.uint16(), d.string()
+	if d.err != nil {
+		return d.err
+	}
+	if !(protocol == "MQTT" && level == 4) && !(protocol == "MQIsdp" && level == 3) {
+		c.conn.Write((&packet{kind: pktConnAck, body: []byte{0, connBadProtocol}}).encode())
+		return fmt.Errorf("unsupported protocol %s level %d", protocol, level)
+	}
+	if flags&0x04 != 0 {
+		topic, payload := d.string(), d.bytes()
+		c.will = &Message{
+			Topic:   topic,
+			Payload: append([]byte(nil), payload...),
+			QoS:     (flags >> 3) & 3,
+			Retain:  flags&0x20 != 0,
+		}
+		if c.will.QoS > 1 {
+			c.will.QoS = 1
+		}
+	}
+	if flags&0x80 != 0 {
+		d.string() // user name is not checked
+	}
+	if flags&0x40 != 0 {
+		d.bytes() // password is not checked
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if c.will != nil && !validTopic(c.will.Topic) {
+		return fmt.Errorf("invalid will topic %q", c.will.Topic)
+	}
+	if id == "" && flags&0x02 == 0 {
+		c.conn.Write((&packet{kind: pktConnAck, body: []byte{0, connIdentifierRejected}}).encode())
+		return fmt.Errorf("empty client ID requires clean session")
+	}
+
+	c.id = id
+	c.keepAlive = time.Duration(keepAlive) * time.Second
+	c.subs = make(map[string]byte)
+	c.out = make(chan *packet, c.broker.queueSize())
+	c.done = make(chan struct{})
+	if err = c.broker.register(c); err != nil {
+		return err
+	}
+	go c.writeLoop()
+	c.send(&packet{kind: pktConnAck, body: []byte{0, connAccepted}}, false)
+	c.broker.debug("client connected", "client", c.id, "remote", c.conn.RemoteAddr().String())
+	return nil
+}
+
+// serve handles the packets until the connection is closed,
+// nil is returned when the client disconnects gracefully
+func (c *client) serve() error {
+	for {
+		if c.keepAlive > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.keepAlive * 3 / 2))
+		} else {
+			c.conn.SetReadDeadline(time.Time{})
+		}
+		p, err := readPacket(c.r, c.broker.maxPacketSize())
+		if err != nil {
+			return err
+		}
+		switch p.kind {
+		case pktPublish:
+			err = c.handlePublish(p)
+		case pktPubAck:
+			// sessions are not persisted, nothing to resend
+		case pktSubscribe:
+			err = c.handleSubscribe(p)
+		case pktUnsubscribe:
+			err = c.handleUnsubscribe(p)
+		case pktPingReq:
+			c.send(&packet{kind: pktPingResp}, false)
+		case pktDisconnect:
+			c.takeWill()
+			return nil
+		default:
+			err = fmt.Errorf("unexpected packet type %d", p.kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (c *client) handlePublish(p *packet) error {
+	qos := (p.flags >> 1) & 3
+	d := &decoder{data: p.body}
+	topic := d.string()
+	var id uint16
+	if qos > 0 {
+		id = d.uint16()
+	}
+	if d.err != nil {
+		return d.err
+	}
+	if qos > 1 {
+		return fmt.Errorf("QoS %d not supported", qos)
+	}
+	if !validTopic(topic) {
+		return fmt.Errorf("invalid topic %q", topic)
+	}
+	c.broker.Publish(&Message{Topic: topic, Payload: d.data, QoS: qos, Retain: p.flags&1 != 0})
+	if qos == 1 {
+		c.send(ackPacket(pktPubAck, id), false)
+	}
+	return nil
+}
+
+func (c *client) handleSubscribe(p *packet) error {
+	if p.flags != 2 {
+		return errMalformed
+	}
+	d := &decoder{data: p.body}
+	id := d.uint16()
+	var filters []string
+	var codes []byte
+	for d.err == nil && !d.empty() {
+		filter, qos := d.string(), d.byte()
+		if d.err != nil {
+			break
+		}
+		if !validFilter(filter) || qos > 2 {
+			codes = append(codes, subFailure)
+			continue
+		}
+		if qos > 1 {
+			qos = 1
+		}
+		c.lock.Lock()
+		c.subs[filter] = qos
+		c.lock.Unlock()
+		filters = append(filters, filter)
+		codes = append(codes, qos)
+	}
+	if d.err != nil || len(codes) == 0 {
+		return errMalformed
+	}
+	c.send(&packet{kind: pktSubAck, body: append(appendUint16(nil, id), codes...)}, false)
+	for _, filter := range filters {
+		qos := c.subs[filter]
+		for _, msg := range c.broker.retainedFor(filter) {
+			c.deliver(msg, qos, true)
+		}
+	}
+	return nil
+}
+
+func (c *client) handleUnsubscribe(p *packet) error {
+	if p.flags != 2 {
+		return errMalformed
+	}
+	d := &decoder{data: p.body}
+	id := d.uint16()
+	for d.err == nil && !d.empty() {
+		filter := d.string()
+		c.lock.Lock()
+		delete(c.subs, filter)
+		c.lock.Unlock()
+	}
+	if d.err != nil {
+		return d.err
+	}
+	c.send(ackPacket(pktUnsubAck, id), false)
+	return nil
+}
+
+// subscribed returns the maximum QoS of the filters matching the topic
+func (c *client) subscribed(topic string) (byte, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var qos byte
+	matched := false
+	for filter, q := range c.subs {
+		if MatchTopic(filter, topic) {
+			matched = true
+			if q > qos {
+				qos = q
+			}
+		}
+	}
+	return qos, matched
+}
+
+func (c *client) deliver(msg *Message, qos byte, retain bool) {
+	if msg.QoS < qos {
+		qos = msg.QoS
+	}
+	var id uint16
+	if qos > 0 {
+		c.lock.Lock()
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID++
+		}
+		id = c.packetID
+		c.lock.Unlock()
+	}
+	c.send(publishPacket(msg, qos, retain, id), true)
+}
+
+// send queues the packet, application messages are dropped
+// when the queue is full to keep slow clients from blocking others
+func (c *client) send(p *packet, drop bool) {
+	if drop {
+		select {
+		case c.out <- p:
+		case <-c.done:
+		default:
+			c.broker.debug("queue full, message dropped", "client", c.id)
+		}
+		return
+	}
+	select {
+	case c.out <- p:
+	case <-c.done:
+	}
+}
+
+func (c *client) writeLoop() {
+	w := bufio.NewWriter(c.conn)
+	for {
+		select {
+		case p := <-c.out:
+			_, err := w.Write(p.encode())
+			if err == nil && len(c.out) == 0 {
+				err = w.Flush()
+			}
+			if err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *client) takeWill() *Message {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	will := c.will
+	c.will = nil
+	return will
+}
+
+func (c *client) close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		c.conn.Close()
+	}
+}
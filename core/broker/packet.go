@@ -0,0 +1,166 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MQTT control packet types
+const (
+	pktConnect     byte = 1
+	pktConnAck     byte = 2
+	pktPublish     byte = 3
+	pktPubAck      byte = 4
+	pktPubRec      byte = 5
+	pktPubRel      byte = 6
+	pktPubComp     byte = 7
+	pktSubscribe   byte = 8
+	pktSubAck      byte = 9
+	pktUnsubscribe byte = 10
+	pktUnsubAck    byte = 11
+	pktPingReq     byte = 12
+	pktPingResp    byte = 13
+	pktDisconnect  byte = 14
+)
+
+// CONNACK return codes
+const (
+	connAccepted           byte = 0
+	connBadProtocol        byte = 1
+	connIdentifierRejected byte = 2
+)
+
+// subFailure is the SUBACK return code of a rejected filter
+const subFailure byte = 0x80
+
+var errMalformed = errors.New("malformed packet")
+
+// packet is a raw MQTT control packet
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func readPacket(r *bufio.Reader, maxSize int) (*packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	size, mul := 0, 1
+	for n := 0; ; n++ {
+		if n >= 4 {
+			return nil, errMalformed
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size += int(b&0x7f) * mul
+		mul <<= 7
+		if b&0x80 == 0 {
+			break
+		}
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, fmt.Errorf("packet size %d exceeds limit %d", size, maxSize)
+	}
+	p := &packet{kind: header >> 4, flags: header & 0x0f, body: make([]byte, size)}
+	if _, err = io.ReadFull(r, p.body); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// encode serializes the packet with the fixed header
+func (p *packet) encode() []byte {
+	size := len(p.body)
+	out := make([]byte, 0, size+5)
+	out = append(out, p.kind<<4|p.flags)
+	for {
+		b := byte(size & 0x7f)
+		size >>= 7
+		if size > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if size == 0 {
+			break
+		}
+	}
+	return append(out, p.body...)
+}
+
+// decoder reads fields from a packet body, the first error is kept
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil || len(d.data) < 1 {
+		d.err = errMalformed
+		return 0
+	}
+	b := d.data[0]
+	d.data = d.data[1:]
+	return b
+}
+
+func (d *decoder) uint16() uint16 {
+	if d.err != nil || len(d.data) < 2 {
+		d.err = errMalformed
+		return 0
+	}
+	v := binary.BigEndian.Uint16(d.data)
+	d.data = d.data[2:]
+	return v
+}
+
+func (d *decoder) bytes() []byte {
+	size := int(d.uint16())
+	if d.err != nil || len(d.data) < size {
+		d.err = errMalformed
+		return nil
+	}
+	b := d.data[:size]
+	d.data = d.data[size:]
+	return b
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes())
+}
+
+func (d *decoder) empty() bool {
+	return len(d.data) == 0
+}
+
+func appendUint16(out []byte, v uint16) []byte {
+	return append(out, byte(v>>8), byte(v))
+}
+
+func appendString(out []byte, s string) []byte {
+	return append(appendUint16(out, uint16(len(s))), s...)
+}
+
+// publishPacket encodes a PUBLISH packet, id is used only for QoS > 0
+func publishPacket(msg *Message, qos byte, retain bool, id uint16) *packet {
+	p := &packet{kind: pktPublish, flags: qos << 1}
+	if retain {
+		p.flags |= 1
+	}
+	body := appendString(make([]byte, 0, len(msg.Topic)+len(msg.Payload)+4), msg.Topic)
+	if qos > 0 {
+		body = appendUint16(body, id)
+	}
+	p.body = append(body, msg.Payload...)
+	return p
+}
+
+func ackPacket(kind byte, id uint16) *packet {
+	return &packet{kind: kind, body: appendUint16(nil, id)}
+}
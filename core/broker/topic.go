@@ -0,0 +1,44 @@
+package broker
+
+import "strings"
+
+// MatchTopic determines if the topic matches the filter with wildcards,
+// topics starting with $ are not matched by wildcards at the first level
+func MatchTopic(filter, topic string) bool {
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	levels, parts := strings.Split(topic, "/"), strings.Split(filter, "/")
+	for n, part := range parts {
+		if part == "#" {
+			return true
+		}
+		if n >= len(levels) || (part != "+" && part != levels[n]) {
+			return false
+		}
+	}
+	return len(parts) == len(levels)
+}
+
+// validFilter checks the wildcards occupy entire levels
+// and # is the last level
+func validFilter(filter string) bool {
+	if filter == "" {
+		return false
+	}
+	parts := strings.Split(filter, "/")
+	for n, part := range parts {
+		if strings.ContainsAny(part, "+#") && len(part) > 1 {
+			return false
+		}
+		if part == "#" && n != len(parts)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// validTopic checks the topic name of a PUBLISH has no wildcards
+func validTopic(topic string) bool {
+	return topic != "" && !strings.ContainsAny(topic, "+#")
+}
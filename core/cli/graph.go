@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/robotalks/talk/core/engine"
+)
+
+// Graph formats
+const (
+	GraphDOT     = "dot"
+	GraphMermaid = "mermaid"
+)
+
+// WriteGraph renders the component graph in the specified format
+func WriteGraph(w io.Writer, g *engine.Graph, format string) error {
+	switch format {
+	case "", GraphDOT:
+		WriteDOT(w, g)
+	case GraphMermaid:
+		WriteMermaid(w, g)
+	default:
+		return fmt.Errorf("unknown graph format %s", format)
+	}
+	return nil
+}
+
+// WriteDOT renders the component graph as Graphviz DOT
+func WriteDOT(w io.Writer, g *engine.Graph) {
+	fmt.Fprintf(w, "digraph %s {\n", strconv.Quote(g.Name))
+	fmt.Fprintln(w, "  rankdir=BT;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(w, "  %s [label=%s];\n", strconv.Quote(node.ID), strconv.Quote(nodeLabel(node, "\n")))
+	}
+	for _, path := range g.HubPaths {
+		fmt.Fprintf(w, "  %s [shape=cds, style=dashed];\n", strconv.Quote(path))
+	}
+	for n, rank := range g.Ranks {
+		fmt.Fprintf(w, "  subgraph rank%d {\n    rank=same;", n)
+		for _, id := range rank {
+			fmt.Fprintf(w, " %s;", strconv.Quote(id))
+		}
+		fmt.Fprintln(w, "\n  }")
+	}
+	for _, edge := range g.Edges {
+		var attrs string
+		switch edge.Kind {
+		case engine.EdgeChild:
+			attrs = "arrowhead=diamond, color=gray"
+		case engine.EdgeAfter:
+			attrs = "style=dotted"
+		case engine.EdgeHub:
+			attrs = "style=dashed"
+		}
+		if edge.Label != "" {
+			if attrs != "" {
+				attrs += ", "
+			}
+			attrs += "label=" + strconv.Quote(edge.Label)
+		}
+		if attrs != "" {
+			attrs = " [" + attrs + "]"
+		}
+		fmt.Fprintf(w, "  %s -> %s%s;\n", strconv.Quote(edge.From), strconv.Quote(edge.To), attrs)
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// WriteMermaid renders the component graph as Mermaid flowchart,
+// init order groups are rendered as subgraphs
+func WriteMermaid(w io.Writer, g *engine.Graph) {
+	ids := make(map[string]string)
+	nodeID := func(name string) string {
+		id, ok := ids[name]
+		if !ok {
+			id = fmt.Sprintf("n%d", len(ids))
+			ids[name] = id
+		}
+		return id
+	}
+	nodes := make(map[string]*engine.GraphNode)
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+
+	fmt.Fprintln(w, "flowchart BT")
+	for n, rank := range g.Ranks {
+		fmt.Fprintf(w, "  subgraph group%d [\"init group %d\"]\n", n, n)
+		for _, id := range rank {
+			fmt.Fprintf(w, "    %s[\"%s\"]\n", nodeID(id), mermaidEscape(nodeLabel(nodes[id], "<br/>")))
+		}
+		fmt.Fprintln(w, "  end")
+	}
+	for _, path := range g.HubPaths {
+		fmt.Fprintf(w, "  %s[/\"%s\"/]\n", nodeID(path), mermaidEscape(path))
+	}
+	for _, edge := range g.Edges {
+		var arrow string
+		switch edge.Kind {
+		case engine.EdgeChild:
+			arrow = "--o"
+		case engine.EdgeAfter, engine.EdgeHub:
+			arrow = "-.->"
+		default:
+			arrow = "-->"
+		}
+		if edge.Label != "" {
+			arrow += "|" + mermaidEscape(edge.Label) + "|"
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", nodeID(edge.From), arrow, nodeID(edge.To))
+	}
+}
+
+func nodeLabel(node *engine.GraphNode, sep string) string {
+	if node.Type == "" {
+		return node.ID
+	}
+	return node.ID + sep + node.Type
+}
+
+func mermaidEscape(str string) string {
+	return strings.Replace(str, `"`, "#quot;", -1)
+}
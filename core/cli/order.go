@@ -0,0 +1,19 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/robotalks/talk/core/engine"
+)
+
+// PrintInitOrder prints the components in the order they are started
+func PrintInitOrder(w io.Writer, spec *engine.Spec) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tCOMPONENT\tTYPE\tPRIORITY")
+	for _, info := range spec.Topology() {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\n", info.InitGroup, info.ID, info.Type, info.Priority)
+	}
+	return tw.Flush()
+}
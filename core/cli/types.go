@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -42,3 +43,82 @@ func PrintTypes(w io.Writer) {
 		}
 	}
 }
+
+// TypeInfo is the machine-readable form of a component type
+type TypeInfo struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Schema      *v0.ComponentSchema `json:"schema,omitempty"`
+}
+
+// NewTypeInfo creates TypeInfo from a component type
+func NewTypeInfo(t v0.ComponentType) *TypeInfo {
+	info := &TypeInfo{Name: t.Name(), Description: t.Description()}
+	if provider, ok := t.(v0.SchemaProvider); ok {
+		info.Schema = provider.Schema()
+	}
+	return info
+}
+
+// PrintTypesJSON prints known component types with schemas as JSON
+func PrintTypesJSON(w io.Writer) error {
+	types := v0.DefaultComponentTypeRegistry.RegisteredComponentTypes()
+	infos := make([]*TypeInfo, 0, len(types))
+	for _, t := range types {
+		infos = append(infos, NewTypeInfo(t))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(infos)
+}
+
+// DescribeType prints the details of a component type
+func DescribeType(w io.Writer, name string) error {
+	t, err := v0.DefaultComponentTypeRegistry.ResolveComponentType(name)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return fmt.Errorf("unknown type %s", name)
+	}
+	info := NewTypeInfo(t)
+	fmt.Fprintln(w, info.Name)
+	for _, line := range strings.Split(info.Description, "\n") {
+		fmt.Fprintln(w, "  "+line)
+	}
+	if info.Schema == nil {
+		return nil
+	}
+	if len(info.Schema.Config) > 0 {
+		fmt.Fprintln(w, "\nConfig:")
+		for _, f := range info.Schema.Config {
+			key := f.Name
+			if f.Optional {
+				key += "?"
+			}
+			fmt.Fprintf(w, "  %s: %s\n", key, f.Shape)
+		}
+	}
+	if len(info.Schema.Injects) > 0 {
+		fmt.Fprintln(w, "\nInject:")
+		for _, inject := range info.Schema.Injects {
+			kind := "ref"
+			if inject.Hub {
+				kind = "hub"
+			}
+			name := inject.Name
+			if inject.Optional {
+				name += "?"
+			}
+			fmt.Fprintf(w, "  %s: %s (%s)\n", name, inject.Type, kind)
+		}
+	}
+	if len(info.Schema.Endpoints) > 0 {
+		fmt.Fprintln(w, "\nEndpoints:")
+		for _, ep := range info.Schema.Endpoints {
+			fmt.Fprintf(w, "  %s [%s]: %s\n", ep.Name, ep.Direction, ep.Payload)
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 
 	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
 )
 
 // CastTarget defines a target to cast raw data to
@@ -27,6 +28,8 @@ type UDPCast struct {
 	BindAddr string
 	Address  string
 	Conn     *net.UDPConn
+	// SentBytes optionally counts the bytes sent
+	SentBytes v0.Counter
 
 	remote *net.UDPAddr
 }
@@ -64,7 +67,11 @@ func (c *UDPCast) Close() error {
 func (c *UDPCast) Cast(data []byte) mqhub.Future {
 	f := &mqhub.ImmediateFuture{}
 	if c.remote != nil {
-		_, f.Error = c.Conn.WriteToUDP(data, c.remote)
+		var n int
+		n, f.Error = c.Conn.WriteToUDP(data, c.remote)
+		if c.SentBytes != nil {
+			c.SentBytes.Add(float64(n))
+		}
 		if f.Error != nil {
 			println(f.Error.Error(), len(data))
 		}
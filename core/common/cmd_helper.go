@@ -25,3 +25,44 @@ func StopCmd(cmd *exec.Cmd) error {
 	proc.Kill()
 	return cmd.Wait()
 }
+
+// Process is a started command watched in background
+type Process struct {
+	Cmd *exec.Cmd
+
+	err  error
+	done chan struct{}
+}
+
+// StartProcess starts the command and calls onExit when it exits
+func StartProcess(cmd *exec.Cmd, onExit func(error)) (*Process, error) {
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Process{Cmd: cmd, done: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.done)
+		if onExit != nil {
+			onExit(p.err)
+		}
+	}()
+	return p, nil
+}
+
+// Stop gracefully stop the process, if not, kill it
+func (p *Process) Stop() error {
+	select {
+	case <-p.done:
+		return p.err
+	default:
+	}
+	p.Cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-time.After(CmdStopTimeout):
+		p.Cmd.Process.Kill()
+		<-p.done
+	case <-p.done:
+	}
+	return p.err
+}
@@ -3,8 +3,10 @@ package engine
 import (
 	"fmt"
 	"reflect"
+	"sort"
 
 	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/mqhub.go/mqhub"
 	"github.com/robotalks/talk/contract/v0"
 )
 
@@ -16,10 +18,19 @@ func (f ComponentFactoryFunc) CreateComponent(ref v0.ComponentRef) (v0.Component
 	return f(ref)
 }
 
-// ConfigComponent is a helper to map configuration into component
+// ConfigComponent is a helper to map configuration into component,
+// unknown keys and values of mismatched types are rejected
 func ConfigComponent(comp v0.Component, ref v0.ComponentRef) error {
-	conf := &MapConfig{Map: ref.ComponentConfig()}
-	return conf.As(comp)
+	return componentConfig(ref, ref.ComponentConfig()).As(comp)
+}
+
+// componentConfig creates the strict config of the component
+func componentConfig(ref v0.ComponentRef, config map[string]interface{}) *MapConfig {
+	conf := &MapConfig{Map: config, Strict: true, Name: ref.MessagePath()}
+	if spec, ok := ref.(*ComponentSpec); ok {
+		conf.Positions = spec.configPositions()
+	}
+	return conf
 }
 
 // SetupComponent is a helper to initialize a component using reflect
@@ -29,55 +40,81 @@ func SetupComponent(comp v0.Component, ref v0.ComponentRef) error {
 		panic("not a struct")
 	}
 	errs := errors.AggregatedError{}
-	errs.Add(ConfigComponent(comp, ref))
-	t := v.Type()
-	injectMap := make(map[string]int)
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if f.PkgPath != "" || f.Anonymous { // unexported or anonymous field
+	fields := InjectFields(v.Type())
+	injections := ref.Injections()
+	errs.Add(componentConfig(ref, configWithValues(ref.ComponentConfig(), v.Type(), injections)).As(comp))
+	names := make([]string, 0, len(injections))
+	for name := range injections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	injected := make(map[string]bool)
+	for _, name := range names {
+		f, key := findInjectField(fields, name)
+		if f == nil {
 			continue
 		}
-		if f.Type.Kind() != reflect.Interface {
-			continue
+		fv := v.FieldByIndex(f.Field.Index)
+		if !fv.CanSet() {
+			panic("field " + f.Field.Name + " must be settable")
+		}
+		if !injected[f.Name] {
+			// injected values replace the default
+			injected[f.Name] = true
+			fv.Set(reflect.Zero(f.Field.Type))
+		}
+		values, ok := injections[name].(InjectionList)
+		if !ok {
+			values = InjectionList{injections[name]}
+		}
+		for _, value := range values {
+			if err := f.inject(fv, key, value); err != nil {
+				errs.Add(fmt.Errorf("%s injection %s %v",
+					comp.Ref().MessagePath(), name, err))
+			}
 		}
+	}
 
-		if key := f.Tag.Get("inject"); key != "" {
-			injectMap[key] = i
+	for name, f := range fields {
+		if !injected[name] && !f.Optional {
+			errs.Add(fmt.Errorf("%s injection %s unresolved",
+				comp.Ref().MessagePath(), name))
 		}
 	}
 
-	for name, injection := range ref.Injections() {
-		index, ok := injectMap[name]
-		if !ok {
+	return errs.Aggregate()
+}
+
+// configWithValues merges the value injections (env, file, params or secrets)
+// which are not accepted by inject fields but config fields into config
+func configWithValues(config map[string]interface{}, t reflect.Type, injections map[string]interface{}) map[string]interface{} {
+	fields, configFields := InjectFields(t), ConfigFields(t)
+	merged := config
+	for name, value := range injections {
+		if f, _ := findInjectField(fields, name); f != nil || findConfigField(configFields, name) == nil {
 			continue
 		}
-		delete(injectMap, name)
-		f := t.Field(index)
-		fv := v.Field(index)
-		if !fv.CanSet() {
-			panic("field " + f.Name + " must be settable")
-		}
-		iv := reflect.ValueOf(injection)
-		if ref, ok := injection.(v0.ComponentRef); ok {
-			iv = reflect.ValueOf(ref.Component())
+		switch value.(type) {
+		case v0.ComponentRef, mqhub.EndpointRef, InjectionList:
+			continue
 		}
-		it := iv.Type()
-		if it.AssignableTo(f.Type) {
-			fv.Set(iv)
-		} else if it.ConvertibleTo(f.Type) {
-			fv.Set(iv.Convert(f.Type))
-		} else {
-			errs.Add(fmt.Errorf("%s injection %s type mismatch",
-				comp.Ref().MessagePath(), name))
+		if len(merged) == len(config) {
+			merged = make(map[string]interface{})
+			for key, val := range config {
+				merged[key] = val
+			}
 		}
+		merged[name] = value
 	}
+	return merged
+}
 
-	for name := range injectMap {
-		errs.Add(fmt.Errorf("%s injection %s unresolved",
-			comp.Ref().MessagePath(), name))
+// ReportExit is a helper for a started component to report it exits by itself,
+// err is nil on normal exit
+func ReportExit(comp v0.Component, err error) {
+	if reporter, ok := comp.Ref().(v0.ExitReporter); ok {
+		reporter.ReportExit(comp, err)
 	}
-
-	return errs.Aggregate()
 }
 
 // RegisterComponentTypes registers named component types
@@ -92,6 +129,8 @@ type CustomComponentType struct {
 	TypeName         string
 	TypeDesc         string
 	ComponentFactory v0.ComponentFactory
+	Proto            reflect.Type
+	EndpointSchemas  []*v0.EndpointSchema
 }
 
 // Name implements v0.ComponentType
@@ -109,12 +148,55 @@ func (t *CustomComponentType) Factory() v0.ComponentFactory {
 	return t.ComponentFactory
 }
 
+// ComponentPrototype implements PrototypedComponentType
+func (t *CustomComponentType) ComponentPrototype() reflect.Type {
+	return t.Proto
+}
+
+// Schema implements v0.SchemaProvider
+func (t *CustomComponentType) Schema() *v0.ComponentSchema {
+	schema := &v0.ComponentSchema{Endpoints: t.EndpointSchemas}
+	if t.Proto != nil {
+		schema.Config = ConfigSchema(t.Proto)
+		schema.Injects = InjectSchema(t.Proto)
+	}
+	return schema
+}
+
 // Describe provides type description
 func (t *CustomComponentType) Describe(desc string) *CustomComponentType {
 	t.TypeDesc = desc
 	return t
 }
 
+// Prototype provides the component created by the factory,
+// only the type of proto is used
+func (t *CustomComponentType) Prototype(proto v0.Component) *CustomComponentType {
+	t.Proto = reflect.TypeOf(proto)
+	return t
+}
+
+// DataPoint declares a DataPoint endpoint, payload is a sample of
+// the published value, or nil if the payload is not structured
+func (t *CustomComponentType) DataPoint(name string, payload interface{}) *CustomComponentType {
+	return t.endpoint(name, v0.EndpointDataPoint, payload)
+}
+
+// Reactor declares a Reactor endpoint, payload is a sample of
+// the accepted value, or nil if the payload is not structured
+func (t *CustomComponentType) Reactor(name string, payload interface{}) *CustomComponentType {
+	return t.endpoint(name, v0.EndpointReactor, payload)
+}
+
+func (t *CustomComponentType) endpoint(name, dir string, payload interface{}) *CustomComponentType {
+	t.EndpointSchemas = append(t.EndpointSchemas, &v0.EndpointSchema{
+		Name:      name,
+		Direction: dir,
+		Payload:   PayloadShape(payload),
+	})
+	return t
+}
+
 // Register wraps RegisterInstanceType
 func (t *CustomComponentType) Register() *CustomComponentType {
 	RegisterComponentTypes(t)
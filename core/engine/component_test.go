@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testNoopCtl struct{}
+
+func (c *testNoopCtl) Start() error { return nil }
+func (c *testNoopCtl) Stop() error  { return nil }
+
+type testWiringType struct{}
+
+func (t *testWiringType) Name() string                 { return "test.wiring" }
+func (t *testWiringType) Description() string          { return t.Name() }
+func (t *testWiringType) Factory() v0.ComponentFactory { return t }
+func (t *testWiringType) ComponentPrototype() reflect.Type {
+	return reflect.TypeOf(&testWiring{})
+}
+func (t *testWiringType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	inst := &testWiring{ref: ref, Status: &testNoopCtl{}}
+	return inst, SetupComponent(inst, ref)
+}
+
+type testWiring struct {
+	Status v0.LifecycleCtl           `inject:"status,optional"`
+	Main   *testInstanceA            `inject:"main"`
+	Legs   []v0.LifecycleCtl         `inject:"legs"`
+	Servos map[string]*testInstanceA `inject:"servos,optional"`
+	ref    v0.ComponentRef
+}
+
+func (w *testWiring) Ref() v0.ComponentRef   { return w.ref }
+func (w *testWiring) Type() v0.ComponentType { return &testWiringType{} }
+
+func TestSetupComponentInjections(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, &testWiringType{})
+	spec := tester.spec(`---
+        name: test
+        components:
+          a:
+            type: test.A
+          b:
+            type: test.A
+          w:
+            type: test.wiring
+            inject:
+              main:
+                type: ref
+                id: a
+              legs.1:
+                type: ref
+                id: b
+              legs.0:
+                type: ref
+                id: a
+              servos:
+                type: ref
+                id: a
+              servos.rear:
+                type: ref
+                id: b
+     `)
+	assert.NoError(t, spec.Validate())
+	specT := newSpecTester(t, spec)
+	a := specT.component("a").Instance.(*testInstanceA)
+	b := specT.component("b").Instance.(*testInstanceA)
+	w := specT.component("w").Instance.(*testWiring)
+	assert.IsType(t, &testNoopCtl{}, w.Status)
+	assert.True(t, w.Main == a)
+	assert.Equal(t, []v0.LifecycleCtl{a, b}, w.Legs)
+	assert.Equal(t, map[string]*testInstanceA{"a": a, "rear": b}, w.Servos)
+	assert.NoError(t, spec.Disconnect())
+
+	spec = tester.resolve(`---
+        name: test
+        components:
+          a:
+            type: test.A
+          w:
+            type: test.wiring
+            inject:
+              status:
+                type: ref
+                id: a
+              main:
+                type: ref
+                id: a
+     `)
+	err := spec.Validate()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "injection legs unresolved")
+		assert.NotContains(t, err.Error(), "injection servos unresolved")
+	}
+}
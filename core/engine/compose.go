@@ -0,0 +1,365 @@
+package engine
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LoadOptions customizes loading of the spec file
+type LoadOptions struct {
+	// Profiles are the names of profiles to apply in order
+	Profiles []string
+	// Overrides are in the form of path=value, applied at last
+	Overrides []string
+}
+
+// LoadSpec loads spec from JSON/YAML file and composes it.
+//
+// A spec file may include other spec files using
+//
+//	include:
+//	  - base.yaml
+//
+// the included files are merged first, and the values in the including
+// file take precedence. Relative paths of the secrets, file injections
+// and the file config of components are resolved against the directory
+// of the file declaring them. A spec file may patch the components by path
+//
+//	overlay:
+//	  head/pan:
+//	    config:          # merged into config
+//	      pulse-min: 600
+//	    unset:           # config keys to remove
+//	      - pulse-max
+//	  head/tilt:
+//	    remove: true     # remove the component
+//	  sim/arm:
+//	    component:       # add or replace the component
+//	      type: sim.arm
+//
+// Profiles are named variants, a component with disabled: true is only
+// created when it is enabled by a selected profile
+//
+//	profiles:
+//	  sim:
+//	    enable: [sim/arm]
+//	    disable: [head/camera]
+//	    overlay: {...}
+//
+// The files read are listed in Spec.Files, the spec file first
+func LoadSpec(fn string, opts *LoadOptions) (*Spec, error) {
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+	var files []string
+	raw, err := loadComposed(fn, nil, &files)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyProfiles(raw, opts.Profiles); err != nil {
+		return nil, err
+	}
+	removeDisabled(raw.Map)
+	if err = raw.SetAll(opts.Overrides); err != nil {
+		return nil, err
+	}
+	spec, err := ParseSpec(raw)
+	if err == nil && fn != "-" && fn != "" {
+		spec.Dir = filepath.Dir(fn)
+	}
+	spec.Files = files
+	return spec, err
+}
+
+// loadComposed loads the file with includes and overlay applied,
+// the files read are appended to files
+func loadComposed(fn string, including []string, files *[]string) (*MapConfig, error) {
+	for _, f := range including {
+		if f == fn {
+			return nil, fmt.Errorf("cyclic include %s -> %s", strings.Join(including, " -> "), fn)
+		}
+	}
+	conf := NewMapConfig()
+	*files = append(*files, fn)
+	if err := conf.LoadFile(fn); err != nil {
+		return nil, err
+	}
+	includes, err := stringList(conf.Map["include"])
+	if err != nil {
+		return nil, fmt.Errorf("%s: include %v", fn, err)
+	}
+	delete(conf.Map, "include")
+	chain := append(append([]string{}, including...), fn)
+	merged := make(map[string]interface{})
+	positions := make(Positions)
+	dirs := make(fileDirs)
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) && fn != "-" && fn != "" {
+			inc = filepath.Join(filepath.Dir(fn), inc)
+		}
+		included, err := loadComposed(inc, chain, files)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeMaps(merged, included.Map)
+		for key, pos := range included.Positions {
+			positions[key] = pos
+		}
+		for key, dir := range included.dirs {
+			dirs[key] = dir
+		}
+	}
+	for key, pos := range conf.Positions {
+		positions[key] = pos
+	}
+	conf.Positions = positions
+	if fn != "-" && fn != "" {
+		dirs.record("", conf.Map, filepath.Dir(fn))
+	}
+	conf.dirs = dirs
+	overlay := conf.Map["overlay"]
+	delete(conf.Map, "overlay")
+	conf.Map = mergeMaps(merged, conf.Map)
+	if err = applyOverlay(conf.Map, overlay, dirs, "overlay"); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	dirs.remove("overlay")
+	return conf, nil
+}
+
+// fileDirs maps the dotted paths of values to the directories of
+// the spec files declaring them, relative file paths in the values
+// are resolved against the directories
+type fileDirs map[string]string
+
+// record sets dir for the values under path
+func (d fileDirs) record(path string, v interface{}, dir string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			d.record(joinPath(path, key), item, dir)
+		}
+	case []interface{}:
+		for n, item := range val {
+			d.record(joinPath(path, strconv.Itoa(n)), item, dir)
+		}
+	default:
+		d[path] = dir
+	}
+}
+
+// copy sets the directories of the values under from to the
+// values at the same relative paths under to
+func (d fileDirs) copy(from, to string) {
+	copied := make(fileDirs)
+	for key, dir := range d {
+		if key == from {
+			copied[to] = dir
+		} else if strings.HasPrefix(key, from+".") {
+			copied[to+key[len(from):]] = dir
+		}
+	}
+	for key, dir := range copied {
+		d[key] = dir
+	}
+}
+
+// remove deletes the directories of path and the values under it
+func (d fileDirs) remove(path string) {
+	prefix := path + "."
+	for key := range d {
+		if key == path || strings.HasPrefix(key, prefix) {
+			delete(d, key)
+		}
+	}
+}
+
+// mergeMaps deep merges src into dst, values in src take precedence
+func mergeMaps(dst, src map[string]interface{}) map[string]interface{} {
+	for key, val := range src {
+		srcMap, srcIsMap := val.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[key] = mergeMaps(dstMap, srcMap)
+		} else {
+			dst[key] = val
+		}
+	}
+	return dst
+}
+
+// applyOverlay patches the components in doc, the directories of the
+// patched values are copied from the overlay at path in dirs
+func applyOverlay(doc map[string]interface{}, overlay interface{}, dirs fileDirs, path string) error {
+	if overlay == nil {
+		return nil
+	}
+	patches, ok := overlay.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("overlay must be a map of component paths")
+	}
+	for _, compPath := range sortedMapKeys(patches) {
+		patch, ok := patches[compPath].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("overlay %s: invalid patch", compPath)
+		}
+		parent, id, err := componentParent(doc, compPath)
+		if err != nil {
+			return fmt.Errorf("overlay %s: %v", compPath, err)
+		}
+		patchPath, target := joinPath(path, compPath), componentPath(compPath)
+		if remove, _ := patch["remove"].(bool); remove {
+			delete(parent, id)
+			dirs.remove(target)
+			continue
+		}
+		if comp, exists := patch["component"]; exists {
+			if _, ok := comp.(map[string]interface{}); !ok {
+				return fmt.Errorf("overlay %s: component must be a map", compPath)
+			}
+			parent[id] = comp
+			dirs.remove(target)
+			dirs.copy(patchPath+".component", target)
+		}
+		comp, ok := parent[id].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("overlay %s: component not found", compPath)
+		}
+		if config, exists := patch["config"]; exists {
+			configMap, ok := config.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("overlay %s: config must be a map", compPath)
+			}
+			current, _ := comp["config"].(map[string]interface{})
+			if current == nil {
+				current = make(map[string]interface{})
+			}
+			comp["config"] = mergeMaps(current, configMap)
+			dirs.copy(patchPath+".config", target+".config")
+		}
+		unset, err := stringList(patch["unset"])
+		if err != nil {
+			return fmt.Errorf("overlay %s: unset %v", compPath, err)
+		}
+		if current, ok := comp["config"].(map[string]interface{}); ok {
+			for _, key := range unset {
+				delete(current, key)
+				dirs.remove(target + ".config." + key)
+			}
+		}
+	}
+	return nil
+}
+
+func applyProfiles(raw *MapConfig, names []string) error {
+	profiles, _ := raw.Map["profiles"].(map[string]interface{})
+	delete(raw.Map, "profiles")
+	for _, name := range names {
+		profile, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unknown profile %s", name)
+		}
+		if err := applyOverlay(raw.Map, profile["overlay"], raw.dirs, "profiles."+name+".overlay"); err != nil {
+			return fmt.Errorf("profile %s: %v", name, err)
+		}
+		for _, action := range []string{"disable", "enable"} {
+			paths, err := stringList(profile[action])
+			if err != nil {
+				return fmt.Errorf("profile %s: %s %v", name, action, err)
+			}
+			for _, compPath := range paths {
+				parent, id, err := componentParent(raw.Map, compPath)
+				if err != nil {
+					return fmt.Errorf("profile %s: %s: %v", name, compPath, err)
+				}
+				comp, ok := parent[id].(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("profile %s: %s: component not found", name, compPath)
+				}
+				comp["disabled"] = action == "disable"
+			}
+		}
+	}
+	raw.dirs.remove("profiles")
+	return nil
+}
+
+// removeDisabled removes the components with disabled: true
+func removeDisabled(doc map[string]interface{}) {
+	comps, _ := doc["components"].(map[string]interface{})
+	for id, val := range comps {
+		comp, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if disabled, _ := comp["disabled"].(bool); disabled {
+			delete(comps, id)
+			continue
+		}
+		delete(comp, "disabled")
+		removeDisabled(comp)
+	}
+}
+
+// componentParent locates the components map containing the component
+// at the path, e.g. head/pan, the map is created if absent
+func componentParent(doc map[string]interface{}, compPath string) (map[string]interface{}, string, error) {
+	ids := strings.Split(strings.Trim(compPath, "/"), "/")
+	node := doc
+	for n, id := range ids {
+		comps, ok := node["components"].(map[string]interface{})
+		if !ok {
+			if node["components"] != nil {
+				return nil, "", fmt.Errorf("invalid components")
+			}
+			comps = make(map[string]interface{})
+			node["components"] = comps
+		}
+		if n == len(ids)-1 {
+			return comps, id, nil
+		}
+		if node, ok = comps[id].(map[string]interface{}); !ok {
+			return nil, "", fmt.Errorf("component %s not found", strings.Join(ids[:n+1], "/"))
+		}
+	}
+	return nil, "", fmt.Errorf("invalid path")
+}
+
+// componentPath converts the component path, e.g. head/pan to
+// the dotted path in the spec, e.g. components.head.components.pan
+func componentPath(compPath string) string {
+	ids := strings.Split(strings.Trim(compPath, "/"), "/")
+	return "components." + strings.Join(ids, ".components.")
+}
+
+func stringList(v interface{}) ([]string, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		list := make([]string, 0, len(val))
+		for _, item := range val {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expect string, got %v", item)
+			}
+			list = append(list, str)
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("expect a list of strings")
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSpecComposition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "talk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	write := func(fn, content string) string {
+		fn = filepath.Join(dir, fn)
+		assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644))
+		return fn
+	}
+	write("base.yaml", `---
+name: robot
+components:
+  head:
+    components:
+      pan:
+        type: test.A
+        config:
+          param: base
+          extra: 1
+      tilt:
+        type: test.A
+      camera:
+        type: test.A
+  sim:
+    type: test.A
+    disabled: true
+profiles:
+  sim:
+    enable: [sim]
+    disable: [head/camera]
+    overlay:
+      head/tilt:
+        config:
+          param: sim
+`)
+	variant := write("bench.yaml", `---
+include:
+  - base.yaml
+components:
+  arm:
+    type: test.A
+overlay:
+  head/pan:
+    config:
+      param: bench
+    unset: [extra]
+  head/tilt:
+    remove: true
+  head/led:
+    component:
+      type: test.A
+`)
+
+	spec, err := LoadSpec(variant, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "robot", spec.Name)
+		assert.Contains(t, spec.ChildSpecs, "arm")
+		assert.NotContains(t, spec.ChildSpecs, "sim")
+		head := spec.ChildSpecs["head"]
+		assert.Equal(t, map[string]interface{}{"param": "bench"}, head.ChildSpecs["pan"].Config)
+		assert.NotContains(t, head.ChildSpecs, "tilt")
+		assert.Contains(t, head.ChildSpecs, "led")
+		assert.Contains(t, head.ChildSpecs, "camera")
+		assert.Equal(t, []string{variant, filepath.Join(dir, "base.yaml")}, spec.Files)
+	}
+
+	spec, err = LoadSpec(filepath.Join(dir, "base.yaml"), &LoadOptions{
+		Profiles:  []string{"sim"},
+		Overrides: []string{"components.head.components.pan.config.param=cli"},
+	})
+	if assert.NoError(t, err) {
+		assert.Contains(t, spec.ChildSpecs, "sim")
+		head := spec.ChildSpecs["head"]
+		assert.NotContains(t, head.ChildSpecs, "camera")
+		assert.Equal(t, "sim", head.ChildSpecs["tilt"].Config["param"])
+		assert.Equal(t, "cli", head.ChildSpecs["pan"].Config["param"])
+	}
+
+	_, err = LoadSpec(variant, &LoadOptions{Profiles: []string{"field"}})
+	assert.Error(t, err)
+
+	write("a.yaml", "include: [b.yaml]\n")
+	write("b.yaml", "include: [a.yaml]\n")
+	_, err = LoadSpec(filepath.Join(dir, "a.yaml"), nil)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "cyclic include")
+	}
+}
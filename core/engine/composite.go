@@ -0,0 +1,249 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// MaxCompositeDepth limits the nesting of composite types
+const MaxCompositeDepth = 16
+
+// CompositeTypeSpec defines a component type composed of child components
+// in the spec. The string values in components may refer to params using
+// $(name), a value which is exactly $(name) takes the type of the param,
+// a param without default value is required.
+//
+//	types:
+//	  pan-tilt:
+//	    description: Pan/Tilt head
+//	    params:
+//	      pan-pin: ~
+//	      tilt-pin: ~
+//	    components:
+//	      pan:
+//	        type: gobot.servo.pwm
+//	        config:
+//	          pin: $(pan-pin)
+type CompositeTypeSpec struct {
+	Description string                 `map:"description"`
+	Params      map[string]interface{} `map:"params"`
+	Components  map[string]interface{} `map:"components"`
+}
+
+// CompositeComponentType is the component type defined by CompositeTypeSpec
+type CompositeComponentType struct {
+	TypeName string
+	Spec     *CompositeTypeSpec
+}
+
+// Name implements v0.ComponentType
+func (t *CompositeComponentType) Name() string {
+	return t.TypeName
+}
+
+// Description implements v0.ComponentType
+func (t *CompositeComponentType) Description() string {
+	if t.Spec.Description != "" {
+		return t.Spec.Description
+	}
+	return "Composite of " + strings.Join(sortedMapKeys(t.Spec.Components), ", ")
+}
+
+// Factory implements v0.ComponentType
+func (t *CompositeComponentType) Factory() v0.ComponentFactory {
+	return ComponentFactoryFunc(func(ref v0.ComponentRef) (v0.Component, error) {
+		return &compositeComponent{ref: ref, typ: t}, nil
+	})
+}
+
+// Expand creates the child components using params from config
+func (t *CompositeComponentType) Expand(config map[string]interface{}) (map[string]*ComponentSpec, error) {
+	params := make(map[string]interface{})
+	for name, value := range t.Spec.Params {
+		params[name] = value
+	}
+	for _, key := range sortedMapKeys(config) {
+		if _, exists := t.Spec.Params[key]; !exists {
+			return nil, fmt.Errorf("unknown param %s for type %s", key, t.TypeName)
+		}
+		params[key] = config[key]
+	}
+	for _, name := range sortedMapKeys(params) {
+		if params[name] == nil {
+			return nil, fmt.Errorf("param %s required by type %s", name, t.TypeName)
+		}
+	}
+	components, err := substituteParams(t.Spec.Components, params)
+	if err != nil {
+		return nil, fmt.Errorf("type %s: %v", t.TypeName, err)
+	}
+	var parsed struct {
+		Components map[string]*ComponentSpec `map:"components"`
+	}
+	conf := &MapConfig{Map: map[string]interface{}{"components": components}}
+	if err := conf.As(&parsed); err != nil {
+		return nil, fmt.Errorf("type %s: %v", t.TypeName, err)
+	}
+	return parsed.Components, nil
+}
+
+func substituteParams(v interface{}, params map[string]interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return substituteString(val, params)
+	case map[string]interface{}:
+		m := make(map[string]interface{})
+		for k, item := range val {
+			result, err := substituteParams(item, params)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = result
+		}
+		return m, nil
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			result, err := substituteParams(item, params)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = result
+		}
+		return list, nil
+	}
+	return v, nil
+}
+
+func substituteString(str string, params map[string]interface{}) (interface{}, error) {
+	var result string
+	for {
+		pos := strings.Index(str, "$(")
+		if pos < 0 {
+			return result + str, nil
+		}
+		end := strings.Index(str[pos:], ")")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated param in %q", str)
+		}
+		name := str[pos+2 : pos+end]
+		value, exists := params[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown param %s", name)
+		}
+		if pos == 0 && result == "" && end == len(str)-1 {
+			return value, nil
+		}
+		result += str[:pos] + fmt.Sprint(value)
+		str = str[pos+end+1:]
+	}
+}
+
+type compositeComponent struct {
+	ref v0.ComponentRef
+	typ v0.ComponentType
+}
+
+func (c *compositeComponent) Ref() v0.ComponentRef   { return c.ref }
+func (c *compositeComponent) Type() v0.ComponentType { return c.typ }
+
+// specTypeRegistry is the registry scoped to a spec, it holds the
+// composite types defined in the spec and falls back to the resolver
+type specTypeRegistry struct {
+	types    map[string]v0.ComponentType
+	resolver v0.ComponentTypeResolver
+}
+
+// ResolveComponentType implements v0.ComponentTypeResolver
+func (r *specTypeRegistry) ResolveComponentType(name string) (v0.ComponentType, error) {
+	if typ, exists := r.types[name]; exists {
+		return typ, nil
+	}
+	return r.resolver.ResolveComponentType(name)
+}
+
+// RegisterComponentType implements v0.ComponentTypeRegistry
+func (r *specTypeRegistry) RegisterComponentType(componentType v0.ComponentType) {
+	r.types[componentType.Name()] = componentType
+}
+
+// RegisteredComponentTypes implements v0.ComponentTypeRegistry
+func (r *specTypeRegistry) RegisteredComponentTypes() (types []v0.ComponentType) {
+	if registry, ok := r.resolver.(v0.ComponentTypeRegistry); ok {
+		for _, t := range registry.RegisteredComponentTypes() {
+			if _, exists := r.types[t.Name()]; !exists {
+				types = append(types, t)
+			}
+		}
+	}
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		types = append(types, r.types[name])
+	}
+	return
+}
+
+// typeResolver returns the registry scoped to the spec with the composite
+// types defined in the spec, which must not conflict with the types of
+// TypeResolver
+func (s *Spec) typeResolver() (v0.ComponentTypeRegistry, error) {
+	resolver := s.TypeResolver
+	if resolver == nil {
+		resolver = v0.DefaultComponentTypeRegistry
+	}
+	registry := &specTypeRegistry{
+		types:    make(map[string]v0.ComponentType),
+		resolver: resolver,
+	}
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if typ, _ := resolver.ResolveComponentType(name); typ != nil {
+			return nil, fmt.Errorf("type %s conflicts with a registered type", name)
+		}
+		registry.RegisterComponentType(&CompositeComponentType{TypeName: name, Spec: s.Types[name]})
+	}
+	return registry, nil
+}
+
+// expand replaces components of composite types with their child components
+func (s *ComponentSpec) expand(resolver v0.ComponentTypeResolver, depth int) error {
+	if s.TypeName != "" && !s.expanded {
+		// unresolved types are reported by resolveType
+		typ, _ := resolver.ResolveComponentType(s.TypeName)
+		if composite, ok := typ.(*CompositeComponentType); ok {
+			if depth >= MaxCompositeDepth {
+				return fmt.Errorf("%s: composite type %s nested too deep", s.FullID(), s.TypeName)
+			}
+			children, err := composite.Expand(s.Config)
+			if err != nil {
+				return fmt.Errorf("%s: %v", s.FullID(), err)
+			}
+			for id, child := range children {
+				if _, exists := s.ChildSpecs[id]; exists {
+					return fmt.Errorf("%s: component %s conflicts with type %s", s.FullID(), id, s.TypeName)
+				}
+				s.ChildSpecs[id] = child
+				child.init(s.Root, id, s)
+			}
+			s.expanded = true
+			depth++
+		}
+	}
+	for _, id := range sortedKeys(s.ChildSpecs) {
+		if err := s.ChildSpecs[id].expand(resolver, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+const compositeSpec = `---
+        name: robot
+        types:
+          pan-tilt:
+            description: Pan/Tilt head
+            params:
+              pan-param: ~
+              tilt-param: tilt
+            components:
+              pan:
+                type: test.A
+                config:
+                  param: $(pan-param)
+              tilt:
+                type: test.A
+                config:
+                  param: head-$(tilt-param)
+              tracker:
+                type: test.B
+                inject:
+                  a:
+                    type: ref
+                    id: pan
+                  ref:
+                    type: hub
+                    path: remote/$(pan-param)/ep
+        components:
+          head:
+            type: pan-tilt
+            config:
+              pan-param: left
+`
+
+func TestCompositeType(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, typeInstanceB)
+	spec := tester.spec(compositeSpec)
+	head := newSpecTester(t, spec).component("head")
+	assert.IsType(t, &CompositeComponentType{}, head.ResolvedType)
+	if assert.Len(t, head.ChildSpecs, 3) {
+		assert.Equal(t, "left", head.ChildSpecs["pan"].Instance.(*testInstanceA).Param)
+		assert.Equal(t, "head-tilt", head.ChildSpecs["tilt"].Instance.(*testInstanceA).Param)
+		tracker := head.ChildSpecs["tracker"].Instance.(*testInstanceB)
+		assert.Equal(t, head.ChildSpecs["pan"].Instance, tracker.Ctl)
+		remote := tracker.Remote.(*testDummyEndpointRef)
+		assert.Contains(t, remote.componentID, "remote/left")
+		assert.Equal(t, "ep", remote.endpoint)
+	}
+	assert.Equal(t, "pan-tilt", head.ResolvedType.Name())
+	assert.Equal(t, "Pan/Tilt head", head.ResolvedType.Description())
+
+	// expanding is done only once
+	assert.NoError(t, spec.Resolve())
+	assert.Len(t, head.ChildSpecs, 3)
+}
+
+func TestCompositeTypeParams(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, typeInstanceB)
+	resolve := func(config string) error {
+		conf := NewMapConfig()
+		assert.NoError(t, conf.Load(bytes.NewBufferString(compositeSpec)))
+		spec, err := ParseSpec(conf)
+		assert.NoError(t, err)
+		spec.ChildSpecs["head"].Config = nil
+		if config != "" {
+			spec.ChildSpecs["head"].Config = map[string]interface{}{config: "x"}
+		}
+		spec.TypeResolver = tester.types
+		return spec.Resolve()
+	}
+	err := resolve("")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "param pan-param required by type pan-tilt")
+	}
+	err = resolve("unknown")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unknown param unknown for type pan-tilt")
+	}
+	assert.NoError(t, resolve("pan-param"))
+}
+
+func TestCompositeTypeScoped(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, typeInstanceB)
+	registry := v0.DefaultComponentTypeRegistry
+	v0.DefaultComponentTypeRegistry = &specTypeRegistry{
+		types:    map[string]v0.ComponentType{typeInstanceA.Name(): typeInstanceA},
+		resolver: tester.types,
+	}
+	defer func() { v0.DefaultComponentTypeRegistry = registry }()
+
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(compositeSpec)))
+	spec, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Resolve())
+	typ, err := v0.DefaultComponentTypeRegistry.ResolveComponentType("pan-tilt")
+	assert.NoError(t, err)
+	assert.Nil(t, typ)
+
+	resolver, err := spec.typeResolver()
+	assert.NoError(t, err)
+	names := make([]string, 0)
+	for _, typ := range resolver.RegisteredComponentTypes() {
+		names = append(names, typ.Name())
+	}
+	assert.Equal(t, []string{typeInstanceA.Name(), "pan-tilt"}, names)
+
+	spec.Types[typeInstanceA.Name()] = spec.Types["pan-tilt"]
+	err = spec.Resolve()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "type test.A conflicts with a registered type")
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/easeway/langx.go/mapper"
 	yaml "gopkg.in/yaml.v2"
@@ -21,6 +23,14 @@ type Config interface {
 // MapConfig implements Config backed by a map
 type MapConfig struct {
 	Map map[string]interface{}
+	// Strict rejects unknown keys and values of mismatched types in As
+	Strict bool
+	// Name prefixes the errors in strict mode, e.g. the full ID of component
+	Name string
+	// Positions locates the values in the source file
+	Positions Positions
+
+	dirs fileDirs
 }
 
 // NewMapConfig creates a MapConfig
@@ -33,7 +43,18 @@ func (c *MapConfig) As(out interface{}) error {
 	if c.Map == nil {
 		return nil
 	}
-	return mapper.Map(out, c.Map)
+	if c.Strict {
+		checker := &strictChecker{name: c.Name, positions: c.Positions}
+		checker.check(reflect.TypeOf(out), c.Map, "")
+		if err := checker.errs.Aggregate(); err != nil {
+			return err
+		}
+	}
+	parsed, err := parseConfigValues(reflect.TypeOf(out), c.Map)
+	if err != nil {
+		return err
+	}
+	return mapper.Map(out, parsed)
 }
 
 // LoadFile loads config from JSON/YAML
@@ -45,10 +66,18 @@ func (c *MapConfig) LoadFile(fn string) error {
 	if err == nil {
 		err = c.Load(bytes.NewBuffer(content))
 	}
+	if err == nil {
+		c.Positions.setFile(fn)
+	}
 	return err
 }
 
-// Load loads config from a stream
+// Load loads config from a stream.
+// ${VAR} and ${VAR:-default} in string values are replaced by
+// environment variables, ${VAR} is kept as is if VAR is not defined.
+// $${VAR} is loaded as ${VAR} to pass it to a shell command without
+// being replaced, $$ not followed by { is kept as is.
+// The lines of YAML values are recorded in Positions
 func (c *MapConfig) Load(stream io.Reader) error {
 	content, err := ioutil.ReadAll(stream)
 	if err != nil {
@@ -58,8 +87,9 @@ func (c *MapConfig) Load(stream io.Reader) error {
 		c.Map = make(map[string]interface{})
 	}
 	if bytes.HasPrefix(bytes.TrimSpace(content), []byte{'{'}) {
-		err = json.Unmarshal(content, c.Map)
+		err = json.Unmarshal(content, &c.Map)
 	} else {
+		c.Positions = yamlPositions(content)
 		err = yaml.Unmarshal(content, c.Map)
 		if err == nil {
 			if m, ok := mapper.StringifyKeys(c.Map).(map[string]interface{}); ok {
@@ -69,5 +99,143 @@ func (c *MapConfig) Load(stream io.Reader) error {
 			}
 		}
 	}
+	if err == nil {
+		var m interface{}
+		if m, err = interpolate(c.Map); err == nil {
+			c.Map = m.(map[string]interface{})
+		}
+	}
 	return err
 }
+
+// Set overrides the value at path, e.g. components.servo.config.pin,
+// the value is parsed as YAML scalar
+func (c *MapConfig) Set(path string, value string) error {
+	if c.Map == nil {
+		c.Map = make(map[string]interface{})
+	}
+	keys := strings.Split(path, ".")
+	m := c.Map
+	for n, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			if m[key] != nil {
+				return fmt.Errorf("set %s: %s is not a map", path, strings.Join(keys[:n+1], "."))
+			}
+			next = make(map[string]interface{})
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = parseScalar(value)
+	c.Positions.remove(path)
+	c.dirs.remove(path)
+	return nil
+}
+
+// SetAll applies overrides in the form of path=value
+func (c *MapConfig) SetAll(overrides []string) error {
+	for _, override := range overrides {
+		pos := strings.Index(override, "=")
+		if pos <= 0 {
+			return fmt.Errorf("invalid override %q, expect path=value", override)
+		}
+		if err := c.Set(override[:pos], override[pos+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseScalar(str string) interface{} {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(str), &v); err != nil {
+		return str
+	}
+	switch v.(type) {
+	case map[interface{}]interface{}, []interface{}, nil:
+		return str
+	}
+	return v
+}
+
+func interpolate(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return interpolateString(val)
+	case map[string]interface{}:
+		for k, item := range val {
+			result, err := interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = result
+		}
+	case []interface{}:
+		for i, item := range val {
+			result, err := interpolate(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = result
+		}
+	}
+	return v, nil
+}
+
+// interpolateString expands variables in str, when str is a single
+// variable reference, the expanded value is parsed as YAML scalar
+func interpolateString(str string) (interface{}, error) {
+	if !strings.Contains(str, "$") {
+		return str, nil
+	}
+	var expanded []byte
+	refs := 0
+	for i := 0; i < len(str); i++ {
+		if str[i] != '$' || i+1 >= len(str) {
+			expanded = append(expanded, str[i])
+			continue
+		}
+		switch str[i+1] {
+		case '$':
+			// $${ is escaped, other $$ is kept, e.g. the PID in a shell
+			if i+2 < len(str) && str[i+2] == '{' {
+				expanded = append(expanded, '$')
+			} else {
+				expanded = append(expanded, '$', '$')
+			}
+			i++
+			continue
+		case '{':
+		default:
+			expanded = append(expanded, str[i])
+			continue
+		}
+		end := strings.Index(str[i:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated variable in %q", str)
+		}
+		ref := str[i+2 : i+end]
+		name, def, hasDef := ref, "", false
+		if pos := strings.Index(ref, ":-"); pos >= 0 {
+			name, def, hasDef = ref[:pos], ref[pos+2:], true
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok && !hasDef {
+			// leave it to the consumer, e.g. a shell command
+			expanded = append(expanded, str[i:i+end+1]...)
+			i += end
+			continue
+		}
+		if value == "" && hasDef {
+			value = def
+		}
+		expanded = append(expanded, value...)
+		refs++
+		i += end
+		if refs == 1 && i == len(str)-1 && strings.HasPrefix(str, "${") {
+			return parseScalar(string(expanded)), nil
+		}
+	}
+	return string(expanded), nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigInterpolation(t *testing.T) {
+	os.Setenv("TALK_TEST_PORT", "/dev/ttyUSB1")
+	os.Setenv("TALK_TEST_ADDR", "0x41")
+	os.Unsetenv("TALK_TEST_UNSET")
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        port: ${TALK_TEST_PORT}
+        addr: ${TALK_TEST_ADDR}
+        rate: ${TALK_TEST_UNSET:-50}
+        url: tcp://${TALK_TEST_UNSET:-localhost}:1883
+        price: $$5
+        shell: echo $${TALK_TEST_PORT} ${TALK_TEST_UNSET}
+        unset: ${TALK_TEST_UNSET}
+        list:
+          - ${TALK_TEST_PORT}
+     `)))
+	assert.Equal(t, "/dev/ttyUSB1", conf.Map["port"])
+	assert.Equal(t, 0x41, conf.Map["addr"])
+	assert.Equal(t, 50, conf.Map["rate"])
+	assert.Equal(t, "tcp://localhost:1883", conf.Map["url"])
+	assert.Equal(t, "$$5", conf.Map["price"])
+	assert.Equal(t, "echo ${TALK_TEST_PORT} ${TALK_TEST_UNSET}", conf.Map["shell"])
+	assert.Equal(t, "${TALK_TEST_UNSET}", conf.Map["unset"])
+	assert.Equal(t, []interface{}{"/dev/ttyUSB1"}, conf.Map["list"])
+
+	assert.Error(t, NewMapConfig().Load(bytes.NewBufferString(`port: ${TALK_TEST_PORT`)))
+}
+
+func TestConfigShellEscape(t *testing.T) {
+	os.Setenv("TALK_TEST_PORT", "/dev/ttyUSB1")
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        components:
+          sh:
+            type: shell
+            config:
+              command: echo $$ > /tmp/talk.pid && stty -F $${TALK_TEST_PORT} && echo $$$${TALK_TEST_PORT} $$
+     `)))
+	sh := conf.Map["components"].(map[string]interface{})["sh"].(map[string]interface{})
+	assert.Equal(t, "echo $$ > /tmp/talk.pid && stty -F ${TALK_TEST_PORT} && echo $$${TALK_TEST_PORT} $$",
+		sh["config"].(map[string]interface{})["command"])
+}
+
+func TestConfigOverrides(t *testing.T) {
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        components:
+          servo:
+            type: gpio.servo
+            config:
+              pin: 11
+     `)))
+	assert.NoError(t, conf.SetAll([]string{
+		"components.servo.config.pin=12",
+		"components.servo.config.invert=true",
+		"components.led.type=gpio.led",
+		"name=robot-2",
+	}))
+	servo := conf.Map["components"].(map[string]interface{})["servo"].(map[string]interface{})
+	assert.Equal(t, 12, servo["config"].(map[string]interface{})["pin"])
+	assert.Equal(t, true, servo["config"].(map[string]interface{})["invert"])
+	assert.Equal(t, "gpio.servo", servo["type"])
+	assert.Equal(t, "robot-2", conf.Map["name"])
+	assert.NotNil(t, conf.Map["components"].(map[string]interface{})["led"])
+	assert.Error(t, conf.SetAll([]string{"name"}))
+	assert.Error(t, conf.SetAll([]string{"name.x=1"}))
+}
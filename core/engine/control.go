@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/easeway/langx.go/errors"
+)
+
+// Lifecycle control commands accepted by the engine component
+const (
+	CommandStart   = "start"
+	CommandStop    = "stop"
+	CommandRestart = "restart"
+)
+
+// StopComponent stops the component and all components depending on it
+func (s *Spec) StopComponent(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	comp, err := s.controllable(id)
+	if err != nil {
+		return err
+	}
+	affected := s.dependentsOf(comp)
+	var errs errors.AggregatedError
+	for i := len(affected); i > 0; i-- {
+		errs.Add(affected[i-1].stop())
+	}
+	errs.Add(s.republish())
+	return errs.Aggregate()
+}
+
+// StartComponent starts the stopped component and brings back
+// the stopped components depending on it
+func (s *Spec) StartComponent(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	comp, err := s.controllable(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkDependencies(comp); err != nil {
+		return err
+	}
+	var errs errors.AggregatedError
+	for _, spec := range s.dependentsOf(comp) {
+		if spec.Instance != nil {
+			continue
+		}
+		if err := s.checkDependencies(spec); err != nil {
+			// depends on other stopped components, brought back with them
+			continue
+		}
+		spec.retries = 0
+		spec.connect(&errs)
+		if err := errs.Aggregate(); err != nil {
+			return err
+		}
+		if err := spec.start(); err != nil {
+			return err
+		}
+	}
+	return s.republish()
+}
+
+// RestartComponent re-creates the component and all components depending on it
+func (s *Spec) RestartComponent(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	comp, err := s.controllable(id)
+	if err != nil {
+		return err
+	}
+	if comp.Instance == nil {
+		return fmt.Errorf("%s is not running", comp.FullID())
+	}
+	comp.retries = 0
+	return s.restart(comp)
+}
+
+// Control executes a lifecycle command on the component
+func (s *Spec) Control(command, id string) error {
+	switch command {
+	case CommandStart:
+		return s.StartComponent(id)
+	case CommandStop:
+		return s.StopComponent(id)
+	case CommandRestart:
+		return s.RestartComponent(id)
+	}
+	return fmt.Errorf("unknown command %s", command)
+}
+
+func (s *Spec) controllable(id string) (*ComponentSpec, error) {
+	if !s.running {
+		return nil, fmt.Errorf("not running")
+	}
+	comp := s.findComponent(strings.Trim(id, "/"))
+	if comp == nil {
+		return nil, fmt.Errorf("unknown component %s", id)
+	}
+	return comp, nil
+}
+
+// checkDependencies ensures the components comp depends on are running
+func (s *Spec) checkDependencies(comp *ComponentSpec) error {
+	for _, dep := range s.dependenciesOf(comp) {
+		if dep.ResolvedType != nil && dep.Instance == nil {
+			return fmt.Errorf("%s: dependency %s is not running", comp.FullID(), dep.FullID())
+		}
+	}
+	return nil
+}
+
+// dependenciesOf returns the components comp directly depends on
+func (s *Spec) dependenciesOf(comp *ComponentSpec) (deps []*ComponentSpec) {
+	id := comp.FullID()
+	for _, group := range s.initOrder {
+		for _, spec := range group {
+			if spec.activates[id] == comp {
+				deps = append(deps, spec)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComponentControl(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.resolve(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          base:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+          a:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: base
+          b:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: a
+          c:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+     `)
+	conn := memhub.NewHub("test").Connector()
+	assert.NoError(t, spec.Connect(conn))
+	assert.NoError(t, spec.Start())
+
+	assert.NoError(t, spec.StopComponent("a"))
+	assert.False(t, compType.created("a")[0].isRunning())
+	assert.False(t, compType.created("b")[0].isRunning())
+	assert.True(t, compType.created("base")[0].isRunning())
+	assert.True(t, compType.created("c")[0].isRunning())
+	assert.Error(t, spec.StartComponent("b"))
+
+	assert.NoError(t, spec.StartComponent("a"))
+	if a := compType.created("a"); assert.Len(t, a, 2) {
+		assert.True(t, a[1].isRunning())
+		b := compType.created("b")
+		if assert.Len(t, b, 2) {
+			assert.True(t, b[1].isRunning())
+			assert.True(t, b[1].Dep == a[1])
+		}
+	}
+
+	assert.NoError(t, spec.RestartComponent("/base"))
+	assert.Len(t, compType.created("base"), 2)
+	assert.Len(t, compType.created("a"), 3)
+	assert.Len(t, compType.created("b"), 3)
+	assert.Len(t, compType.created("c"), 1)
+
+	assert.Error(t, spec.StopComponent("unknown"))
+	assert.Error(t, spec.Control("pause", "a"))
+
+	// control over hub
+	desc := conn.Describe("robot").SubComponent(EngineComponentID)
+	assert.NoError(t, desc.Endpoint(CommandStop).ConsumeMessage(mqhub.MsgFrom("c")).Wait())
+	for i := 0; i < 100 && compType.created("c")[0].isRunning(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, compType.created("c")[0].isRunning())
+	spec.lock.Lock()
+	state, _ := newSpecTester(t, spec).component("c").State()
+	spec.lock.Unlock()
+	assert.Equal(t, StateStopped, state)
+
+	// commands run in arrival order
+	assert.NoError(t, desc.Endpoint(CommandStart).ConsumeMessage(mqhub.MsgFrom("c")).Wait())
+	assert.NoError(t, desc.Endpoint(CommandStop).ConsumeMessage(mqhub.MsgFrom("c")).Wait())
+	assert.NoError(t, desc.Endpoint(CommandStart).ConsumeMessage(mqhub.MsgFrom("c")).Wait())
+	for i := 0; i < 100 && len(compType.created("c")) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if c := compType.created("c"); assert.Len(t, c, 3) {
+		assert.False(t, c[1].isRunning())
+		assert.True(t, c[2].isRunning())
+	}
+
+	assert.NoError(t, spec.Disconnect())
+	assert.Error(t, spec.StartComponent("c"))
+}
+
+func TestStartComponentBlockedDependents(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.resolve(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          a:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+          c:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+          d:
+            type: test.crash
+            after:
+              - c
+            inject:
+              dep:
+                type: ref
+                id: a
+     `)
+	assert.NoError(t, spec.Connect(memhub.NewHub("test").Connector()))
+	assert.NoError(t, spec.Start())
+
+	assert.NoError(t, spec.StopComponent("c"))
+	assert.NoError(t, spec.StopComponent("a"))
+	assert.NoError(t, spec.StartComponent("a"))
+	assert.Len(t, compType.created("a"), 2)
+	assert.Len(t, compType.created("d"), 1)
+	assert.Nil(t, newSpecTester(t, spec).component("d").Instance)
+
+	assert.NoError(t, spec.StartComponent("c"))
+	if d := compType.created("d"); assert.Len(t, d, 2) {
+		assert.True(t, d[1].isRunning())
+		assert.True(t, d[1].Dep == compType.created("a")[1])
+	}
+	assert.NoError(t, spec.Disconnect())
+}
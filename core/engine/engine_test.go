@@ -2,11 +2,13 @@ package engine
 
 import (
 	"bytes"
+	"fmt"
 	"path"
 	"testing"
 
 	"github.com/robotalks/mqhub.go/mqhub"
 	"github.com/robotalks/talk/contract/v0"
+	"github.com/robotalks/talk/core/memhub"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -109,13 +111,18 @@ func (t *tester) addTypes(types ...v0.ComponentType) {
 	}
 }
 
-func (t *tester) spec(content string) *Spec {
+func (t *tester) resolve(content string) *Spec {
 	conf := NewMapConfig()
 	t.assert.NoError(conf.Load(bytes.NewBufferString(content)))
 	spec, err := ParseSpec(conf)
 	t.assert.NoError(err)
 	spec.TypeResolver = t.types
 	t.assert.NoError(spec.Resolve())
+	return spec
+}
+
+func (t *tester) spec(content string) *Spec {
+	spec := t.resolve(content)
 	t.assert.NoError(spec.Connect(t))
 	return spec
 }
@@ -253,14 +260,21 @@ func (t *testOrderType) onStop(ref v0.ComponentRef) {
 }
 
 type testOrder struct {
-	typ *testOrderType
-	ref v0.ComponentRef
+	Fail bool `map:"fail"`
+	typ  *testOrderType
+	ref  v0.ComponentRef
 }
 
 func (a *testOrder) Ref() v0.ComponentRef   { return a.ref }
 func (a *testOrder) Type() v0.ComponentType { return a.typ }
-func (a *testOrder) Start() error           { a.typ.onStart(a.ref); return nil }
 func (a *testOrder) Stop() error            { a.typ.onStop(a.ref); return nil }
+func (a *testOrder) Start() error {
+	if a.Fail {
+		return fmt.Errorf("start failure")
+	}
+	a.typ.onStart(a.ref)
+	return nil
+}
 
 func TestLifecycleOrders(t *testing.T) {
 	tester := makeTester(t)
@@ -287,7 +301,112 @@ func TestLifecycleOrders(t *testing.T) {
                after:
                  - a0
      `)
+	assert.NoError(t, spec.Start())
 	assert.Equal(t, []string{"l1/a0", "l1/a1", "a", "b"}, compType.start)
 	spec.Disconnect()
 	assert.Equal(t, []string{"b", "a", "l1/a1", "l1/a0"}, compType.stop)
 }
+
+func TestInitOrderPriority(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testOrderType{}
+	tester.addTypes(compType)
+	spec := tester.spec(`---
+        name: test
+        components:
+          e:
+            type: test.order
+          d:
+            type: test.order
+          c:
+            type: test.order
+            priority: 10
+          b:
+            type: test.order
+            after:
+              - c
+          a:
+            type: test.order
+            priority: -1
+     `)
+	assert.NoError(t, spec.Start())
+	assert.Equal(t, []string{"c", "d", "e", "a", "b"}, compType.start)
+	spec.Disconnect()
+	assert.Equal(t, []string{"b", "a", "e", "d", "c"}, compType.stop)
+}
+
+func TestStartRollback(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testOrderType{}
+	tester.addTypes(compType)
+	spec := tester.spec(`---
+        name: test
+        components:
+          a:
+            type: test.order
+          b:
+            type: test.order
+            after:
+              - a
+          c:
+            type: test.order
+            after:
+              - b
+            config:
+              fail: true
+          d:
+            type: test.order
+            after:
+              - c
+     `)
+	err := spec.Start()
+	if assert.Error(t, err) {
+		startErr, ok := err.(*StartError)
+		if assert.True(t, ok) {
+			assert.Equal(t, "c", startErr.ComponentID)
+			assert.Equal(t, []string{"b", "a"}, startErr.Stopped)
+			assert.NoError(t, startErr.StopErr)
+		}
+	}
+	assert.Equal(t, []string{"a", "b"}, compType.start)
+	assert.Equal(t, []string{"b", "a"}, compType.stop)
+	assert.NoError(t, spec.Disconnect())
+	assert.Equal(t, []string{"b", "a"}, compType.stop)
+}
+
+func TestComponentsOnMemHub(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, typeInstanceB)
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        name: test
+        components:
+          a:
+            type: test.A
+          b:
+            type: test.B
+            inject:
+              a:
+                type: ref
+                id: a
+              ref:
+                type: hub
+                path: remote/component/endpoint
+     `)))
+	spec, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	spec.TypeResolver = tester.types
+	assert.NoError(t, spec.Resolve())
+
+	conn := memhub.NewHub("test").Connector()
+	var received []string
+	_, err = conn.Describe("remote/component").Endpoint("endpoint").
+		Watch(mqhub.MessageSinkAs(func(v string) { received = append(received, v) }))
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Connect(conn))
+	b := newSpecTester(t, spec).component("b").Instance.(*testInstanceB)
+	assert.NoError(t, b.Remote.ConsumeMessage(mqhub.MsgFrom("hello")).Wait())
+	assert.Equal(t, []string{"hello"}, received)
+	assert.NoError(t, spec.Disconnect())
+	assert.NoError(t, conn.Close())
+}
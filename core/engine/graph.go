@@ -0,0 +1,92 @@
+package engine
+
+// Kinds of graph edges
+const (
+	EdgeChild = "child"
+	EdgeRef   = "ref"
+	EdgeAfter = "after"
+	EdgeHub   = "hub"
+)
+
+// GraphNode is a component in the graph
+type GraphNode struct {
+	ID   string
+	Type string
+}
+
+// GraphEdge connects a component to a child, a dependency or a hub path
+type GraphEdge struct {
+	From  string
+	To    string
+	Kind  string
+	Label string
+}
+
+// Graph is the resolved component graph,
+// Ranks are the full IDs of components in init order groups
+type Graph struct {
+	Name     string
+	Nodes    []*GraphNode
+	Ranks    [][]string
+	Edges    []*GraphEdge
+	HubPaths []string
+}
+
+// Graph builds the component graph from the resolved spec
+func (s *Spec) Graph() *Graph {
+	g := &Graph{Name: s.Name}
+	hubPaths := make(map[string]bool)
+	for _, group := range s.initOrder {
+		var rank []string
+		for _, comp := range group {
+			id := comp.FullID()
+			rank = append(rank, id)
+			g.Nodes = append(g.Nodes, &GraphNode{ID: id, Type: comp.TypeName})
+			for _, childID := range sortedKeys(comp.ChildSpecs) {
+				g.Edges = append(g.Edges, &GraphEdge{
+					From: id,
+					To:   comp.ChildSpecs[childID].FullID(),
+					Kind: EdgeChild,
+				})
+			}
+			for _, name := range sortedInjections(comp.InjectSpecs) {
+				inject := comp.InjectSpecs[name]
+				var targets []string
+				switch inject.Type {
+				case InjectRef:
+					targets = comp.injectedIDs()[name]
+				case InjectHub:
+					targets = inject.Paths
+					if inject.Path != "" {
+						targets = append([]string{inject.Path}, targets...)
+					}
+					for _, p := range targets {
+						if !hubPaths[p] {
+							hubPaths[p] = true
+							g.HubPaths = append(g.HubPaths, p)
+						}
+					}
+				}
+				for _, target := range targets {
+					g.Edges = append(g.Edges, &GraphEdge{
+						From:  id,
+						To:    target,
+						Kind:  inject.Type,
+						Label: name,
+					})
+				}
+			}
+			for _, after := range comp.After {
+				if target := comp.resolveIDRef(after); target != nil {
+					g.Edges = append(g.Edges, &GraphEdge{
+						From: id,
+						To:   target.FullID(),
+						Kind: EdgeAfter,
+					})
+				}
+			}
+		}
+		g.Ranks = append(g.Ranks, rank)
+	}
+	return g
+}
@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, typeInstanceB)
+	spec := tester.resolve(`---
+        name: robot
+        components:
+          head:
+            components:
+              a:
+                type: test.A
+          b:
+            type: test.B
+            after:
+              - head
+            inject:
+              a:
+                type: ref
+                id: head/a
+              ref:
+                type: hub
+                path: remote/component/endpoint
+     `)
+	g := spec.Graph()
+	assert.Equal(t, "robot", g.Name)
+	assert.Equal(t, [][]string{{"head/a"}, {"head"}, {"b"}}, g.Ranks)
+	if assert.Len(t, g.Nodes, 3) {
+		assert.Equal(t, &GraphNode{ID: "head/a", Type: "test.A"}, g.Nodes[0])
+	}
+	assert.Equal(t, []string{"remote/component/endpoint"}, g.HubPaths)
+	assert.Equal(t, []*GraphEdge{
+		{From: "head", To: "head/a", Kind: EdgeChild},
+		{From: "b", To: "head/a", Kind: EdgeRef, Label: "a"},
+		{From: "b", To: "remote/component/endpoint", Kind: EdgeHub, Label: "ref"},
+		{From: "b", To: "head", Kind: EdgeAfter},
+	}, g.Edges)
+}
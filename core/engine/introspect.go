@@ -0,0 +1,192 @@
+package engine
+
+import (
+	"sync"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// EngineComponentID is the reserved ID of the component publishing
+// engine information alongside the components in the spec
+const EngineComponentID = "$engine"
+
+// Lifecycle states of a component
+const (
+	StateCreated = "created"
+	StateStarted = "started"
+	StateFailed  = "failed"
+	StateStopped = "stopped"
+)
+
+// ComponentInfo describes a resolved component in the topology
+type ComponentInfo struct {
+	ID         string                    `json:"id"`
+	Type       string                    `json:"type,omitempty"`
+	InitGroup  int                       `json:"init-group"`
+	Priority   int                       `json:"priority,omitempty"`
+	Injections map[string]*InjectionSpec `json:"inject,omitempty"`
+	After      []string                  `json:"after,omitempty"`
+	Children   []string                  `json:"children,omitempty"`
+}
+
+// ComponentState is the lifecycle state of a component
+type ComponentState struct {
+	State string `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// engineComponent publishes the topology, lifecycle states and log records
+type engineComponent struct {
+	spec     *Spec
+	topology *mqhub.DataPoint
+	states   *mqhub.DataPoint
+	log      *mqhub.DataPoint
+	controls []*mqhub.Reactor
+	live     bool
+
+	cmdLock  sync.Mutex
+	commands []controlCommand
+	working  bool
+}
+
+type controlCommand struct {
+	cmd, id string
+}
+
+func newEngineComponent(spec *Spec) *engineComponent {
+	c := &engineComponent{
+		spec:     spec,
+		topology: &mqhub.DataPoint{Name: "topology", Retain: true},
+		states:   &mqhub.DataPoint{Name: "states", Retain: true},
+		log:      &mqhub.DataPoint{Name: "log"},
+	}
+	for _, cmd := range []string{CommandStart, CommandStop, CommandRestart} {
+		c.controls = append(c.controls, mqhub.ReactorAs(cmd, c.controlFunc(cmd)))
+	}
+	return c
+}
+
+// ID implements mqhub.Identifier
+func (c *engineComponent) ID() string {
+	return EngineComponentID
+}
+
+// Endpoints implements mqhub.Component
+func (c *engineComponent) Endpoints() []mqhub.Endpoint {
+	endpoints := []mqhub.Endpoint{c.topology, c.states, c.log}
+	for _, r := range c.controls {
+		endpoints = append(endpoints, r)
+	}
+	return endpoints
+}
+
+// controlFunc returns the handler of a lifecycle command whose
+// payload is the full ID of the component
+func (c *engineComponent) controlFunc(cmd string) func(string) {
+	return func(id string) {
+		c.cmdLock.Lock()
+		defer c.cmdLock.Unlock()
+		c.commands = append(c.commands, controlCommand{cmd: cmd, id: id})
+		if !c.working {
+			// the command re-publishes the spec which must not happen
+			// inside the delivery of a message
+			c.working = true
+			go c.runCommands()
+		}
+	}
+}
+
+// runCommands executes the queued commands in arrival order
+func (c *engineComponent) runCommands() {
+	for {
+		c.cmdLock.Lock()
+		if len(c.commands) == 0 {
+			c.working = false
+			c.cmdLock.Unlock()
+			return
+		}
+		command := c.commands[0]
+		c.commands = c.commands[1:]
+		c.cmdLock.Unlock()
+		if err := c.spec.Control(command.cmd, command.id); err != nil {
+			c.spec.Logfln("%s %s: %v", command.cmd, command.id, err)
+		}
+	}
+}
+
+func (c *engineComponent) published() {
+	c.live = true
+	c.topology.Update(c.spec.Topology())
+	c.states.Update(c.spec.States())
+	c.spec.streamLog(c.log)
+}
+
+func (c *engineComponent) unpublished() {
+	c.live = false
+	c.spec.streamLog(nil)
+}
+
+func (c *engineComponent) stateChanged() {
+	if c.live {
+		c.states.Update(c.spec.States())
+	}
+}
+
+// Topology describes all resolved components in init order
+func (s *Spec) Topology() []*ComponentInfo {
+	var infos []*ComponentInfo
+	for n, group := range s.initOrder {
+		for _, comp := range group {
+			info := &ComponentInfo{
+				ID:         comp.FullID(),
+				Type:       comp.TypeName,
+				InitGroup:  n,
+				Priority:   comp.Priority,
+				Injections: comp.InjectSpecs,
+				After:      comp.After,
+			}
+			for _, id := range sortedKeys(comp.ChildSpecs) {
+				info.Children = append(info.Children, comp.ChildSpecs[id].FullID())
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// States returns lifecycle states of all components indexed by full ID
+func (s *Spec) States() map[string]*ComponentState {
+	states := make(map[string]*ComponentState)
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			state, err := comp.State()
+			if state == "" {
+				continue
+			}
+			st := &ComponentState{State: state}
+			if err != nil {
+				st.Error = err.Error()
+			}
+			states[comp.FullID()] = st
+		}
+	}
+	return states
+}
+
+// State returns the lifecycle state and the last error of the component
+func (s *ComponentSpec) State() (string, error) {
+	return s.state, s.lastErr
+}
+
+func (s *ComponentSpec) setState(state string, err error) {
+	if state == StateFailed {
+		s.metrics().Counter("talk_component_failures_total", "Failures of components").Inc()
+	}
+	s.state = state
+	if err != nil {
+		s.lastErr = err
+	}
+	if s.Root != nil && s.Root.engine != nil {
+		s.Root.engine.stateChanged()
+	}
+}
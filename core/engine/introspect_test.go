@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngineComponent(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testOrderType{}
+	tester.addTypes(compType)
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        name: robot
+        components:
+          a:
+            type: test.order
+          l1:
+            components:
+              b:
+                type: test.order
+                after:
+                  - ../a
+     `)))
+	spec, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	spec.TypeResolver = tester.types
+	assert.NoError(t, spec.Resolve())
+
+	conn := memhub.NewHub("test").Connector()
+	assert.NoError(t, spec.Connect(conn))
+	desc := conn.Describe("robot").SubComponent(EngineComponentID)
+
+	var topology []*ComponentInfo
+	_, err = desc.Endpoint("topology").Watch(mqhub.MessageSinkAs(func(v []*ComponentInfo) {
+		topology = v
+	}))
+	assert.NoError(t, err)
+	if assert.Len(t, topology, 3) {
+		assert.Equal(t, "a", topology[0].ID)
+		assert.Equal(t, "test.order", topology[0].Type)
+		assert.Equal(t, 0, topology[0].InitGroup)
+		assert.Equal(t, "l1/b", topology[1].ID)
+		assert.Equal(t, []string{"../a"}, topology[1].After)
+		assert.Equal(t, "l1", topology[2].ID)
+		assert.Equal(t, []string{"l1/b"}, topology[2].Children)
+	}
+
+	var states map[string]*ComponentState
+	_, err = desc.Endpoint("states").Watch(mqhub.MessageSinkAs(func(v map[string]*ComponentState) {
+		states = v
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, StateCreated, states["a"].State)
+
+	assert.NoError(t, spec.Start())
+	assert.Equal(t, StateStarted, states["a"].State)
+	assert.Equal(t, StateStarted, states["l1/b"].State)
+	assert.Nil(t, states["l1"])
+
+	assert.NoError(t, spec.Disconnect())
+	assert.Equal(t, StateStopped, states["a"].State)
+	assert.Equal(t, StateStopped, states["l1/b"].State)
+}
+
+func TestEngineComponentIDReserved(t *testing.T) {
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        name: robot
+        components:
+          $engine:
+            type: test.order
+     `)))
+	spec, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	assert.Error(t, spec.Resolve())
+}
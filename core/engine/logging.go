@@ -0,0 +1,224 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// Log output formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// LogPrefix is default log prefix of text format
+var LogPrefix = "Talk:> "
+
+// LogRecord is a structured log record,
+// Component and Type are empty for the records from the engine
+type LogRecord struct {
+	Time      time.Time              `json:"time"`
+	Level     v0.LogLevel            `json:"level"`
+	Component string                 `json:"component,omitempty"`
+	Type      string                 `json:"type,omitempty"`
+	Message   string                 `json:"msg"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+}
+
+// LogOutput writes log records in text or JSON format
+type LogOutput struct {
+	// Writer receives the formatted records, nothing is written if nil
+	Writer io.Writer
+	Format string
+	Prefix string
+	// Level is the default level of components
+	Level v0.LogLevel
+
+	lock sync.Mutex
+}
+
+// NewLogOutput creates a LogOutput at info level
+func NewLogOutput(w io.Writer, format string) (*LogOutput, error) {
+	switch format {
+	case "":
+		format = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+	return &LogOutput{Writer: w, Format: format, Prefix: LogPrefix, Level: v0.LogInfo}, nil
+}
+
+// Write writes a record
+func (o *LogOutput) Write(r *LogRecord) error {
+	if o.Writer == nil {
+		return nil
+	}
+	var buf bytes.Buffer
+	if o.Format == LogFormatJSON {
+		if err := json.NewEncoder(&buf).Encode(r); err != nil {
+			return err
+		}
+	} else {
+		buf.WriteString(o.Prefix)
+		buf.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+		formatLogText(&buf, r)
+	}
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	_, err := o.Writer.Write(buf.Bytes())
+	return err
+}
+
+// formatLogText writes the record without prefix and time
+func formatLogText(buf *bytes.Buffer, r *LogRecord) {
+	buf.WriteString(strings.ToUpper(r.Level.String()))
+	buf.WriteByte(' ')
+	if r.Component != "" {
+		buf.WriteString(r.Component)
+		if r.Type != "" {
+			buf.WriteString(" [" + r.Type + "]")
+		}
+		buf.WriteString(": ")
+	}
+	buf.WriteString(r.Message)
+	keys := make([]string, 0, len(r.Fields))
+	for key := range r.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		buf.WriteString(" " + key + "=" + formatLogValue(r.Fields[key]))
+	}
+	buf.WriteByte('\n')
+}
+
+func formatLogValue(v interface{}) string {
+	str := fmt.Sprint(v)
+	if str == "" || strings.ContainsAny(str, " \t\n\"=") {
+		return strconv.Quote(str)
+	}
+	return str
+}
+
+// logger implements v0.Logger for a component or the engine
+type logger struct {
+	root   *Spec
+	spec   *ComponentSpec
+	fields []interface{}
+}
+
+func (l *logger) Debug(msg string, fields ...interface{}) { l.log(v0.LogDebug, msg, fields) }
+func (l *logger) Info(msg string, fields ...interface{})  { l.log(v0.LogInfo, msg, fields) }
+func (l *logger) Warn(msg string, fields ...interface{})  { l.log(v0.LogWarn, msg, fields) }
+func (l *logger) Error(msg string, fields ...interface{}) { l.log(v0.LogError, msg, fields) }
+
+func (l *logger) With(fields ...interface{}) v0.Logger {
+	return &logger{
+		root:   l.root,
+		spec:   l.spec,
+		fields: append(append([]interface{}{}, l.fields...), fields...),
+	}
+}
+
+func (l *logger) log(level v0.LogLevel, msg string, fields []interface{}) {
+	root := l.root
+	if l.spec != nil {
+		root = l.spec.Root
+	}
+	if root == nil || level < l.level(root) {
+		return
+	}
+	r := &LogRecord{Time: time.Now(), Level: level, Message: msg}
+	if l.spec != nil {
+		r.Component, r.Type = l.spec.FullID(), l.spec.TypeName
+	}
+	all := append(append([]interface{}{}, l.fields...), fields...)
+	if len(all) > 0 {
+		r.Fields = make(map[string]interface{})
+		for n := 0; n < len(all); n += 2 {
+			key := fmt.Sprint(all[n])
+			if n+1 >= len(all) {
+				r.Fields[key] = nil
+				break
+			}
+			value := all[n+1]
+			if err, ok := value.(error); ok {
+				value = err.Error()
+			}
+			r.Fields[key] = value
+		}
+	}
+	root.writeLog(r)
+}
+
+// level is the nearest log-level in the spec, or the level of LogOutput
+func (l *logger) level(root *Spec) v0.LogLevel {
+	for spec := l.spec; spec != nil; spec = spec.ParentSpec {
+		if level, err := v0.ParseLogLevel(spec.LogLevel); spec.LogLevel != "" && err == nil {
+			return level
+		}
+	}
+	if level, err := v0.ParseLogLevel(root.LogLevel); root.LogLevel != "" && err == nil {
+		return level
+	}
+	if out := root.LogOutput; out != nil {
+		return out.Level
+	}
+	return v0.LogInfo
+}
+
+// Log returns the logger of the engine
+func (s *Spec) Log() v0.Logger {
+	return &logger{root: s}
+}
+
+func (s *Spec) writeLog(r *LogRecord) {
+	if out := s.LogOutput; out != nil {
+		out.Write(r)
+	} else if l := s.Logger; l != nil {
+		var buf bytes.Buffer
+		formatLogText(&buf, r)
+		l.Print(buf.String())
+	}
+	s.logLock.Lock()
+	stream := s.logStream
+	s.logLock.Unlock()
+	if stream != nil {
+		stream.Update(r)
+	}
+}
+
+// streamLog sets the DataPoint streaming the log records, nil to stop
+func (s *Spec) streamLog(dp *mqhub.DataPoint) {
+	s.logLock.Lock()
+	s.logStream = dp
+	s.logLock.Unlock()
+}
+
+// Logger implements v0.LoggerProvider
+func (s *ComponentSpec) Logger() v0.Logger {
+	return &logger{spec: s}
+}
+
+func (s *ComponentSpec) checkLogLevel(errs *errors.AggregatedError) {
+	if s.LogLevel != "" {
+		if _, err := v0.ParseLogLevel(s.LogLevel); err != nil {
+			errs.Add(fmt.Errorf("%s: %v", s.FullID(), err))
+		}
+	}
+	for _, spec := range s.ChildSpecs {
+		spec.checkLogLevel(errs)
+	}
+}
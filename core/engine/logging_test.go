@@ -0,0 +1,130 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+const loggingSpec = `---
+        name: robot
+        log-level: warn
+        components:
+          a:
+            type: test.A
+          head:
+            log-level: debug
+            components:
+              pan:
+                type: test.A
+     `
+
+func TestComponentLogger(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA)
+	spec := tester.resolve(loggingSpec)
+	var buf bytes.Buffer
+	out, err := NewLogOutput(&buf, LogFormatText)
+	assert.NoError(t, err)
+	out.Prefix = ""
+	spec.LogOutput = out
+
+	a := newSpecTester(t, spec).component("a")
+	pan := spec.findComponent("head/pan")
+	a.Logger().Info("dropped")
+	a.Logger().Warn("low battery", "volts", 3.1)
+	pan.Logger().With("channel", 2).Debug("moved", "pos", 0.5, "err", fmt.Errorf("stalled servo"))
+	spec.Log().Warn("engine")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], " WARN a [test.A]: low battery volts=3.1")
+		assert.Contains(t, lines[1], ` DEBUG head/pan [test.A]: moved channel=2 err="stalled servo" pos=0.5`)
+		assert.Contains(t, lines[2], " WARN engine")
+	}
+
+	buf.Reset()
+	out.Format = LogFormatJSON
+	pan.Logger().Error("failed", "pulse", 100)
+	var r LogRecord
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+	assert.Equal(t, v0.LogError, r.Level)
+	assert.Equal(t, "head/pan", r.Component)
+	assert.Equal(t, "test.A", r.Type)
+	assert.Equal(t, "failed", r.Message)
+	assert.Equal(t, map[string]interface{}{"pulse": 100.0}, r.Fields)
+
+	_, err = NewLogOutput(&buf, "xml")
+	assert.Error(t, err)
+}
+
+func TestLegacyLogger(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA)
+	spec := tester.resolve(loggingSpec)
+	var buf bytes.Buffer
+	spec.Logger = log.New(&buf, "", 0)
+
+	a := newSpecTester(t, spec).component("a")
+	v0.LoggerOf(a).Warn("low battery", "volts", 3.1)
+	assert.Equal(t, "WARN a [test.A]: low battery volts=3.1\n", buf.String())
+
+	buf.Reset()
+	var std bytes.Buffer
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+	v0.LoggerOf(nil).With("a", 1).Info("discarded")
+	assert.Empty(t, std.String())
+	v0.LoggerOf(nil).With("a", 1).Error("failed", "err", "timeout")
+	assert.Contains(t, std.String(), "ERROR failed a=1 err=timeout\n")
+	assert.Empty(t, buf.String())
+}
+
+func TestComponentLogStream(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA)
+	spec := tester.resolve(loggingSpec)
+	conn := memhub.NewHub("test").Connector()
+	assert.NoError(t, spec.Connect(conn))
+
+	var records []*LogRecord
+	desc := conn.Describe("robot").SubComponent(EngineComponentID)
+	_, err := desc.Endpoint("log").Watch(mqhub.MessageSinkAs(func(r *LogRecord) {
+		records = append(records, r)
+	}))
+	assert.NoError(t, err)
+	spec.findComponent("head/pan").Logger().Info("moved")
+	if assert.Len(t, records, 1) {
+		assert.Equal(t, "head/pan", records[0].Component)
+		assert.Equal(t, "moved", records[0].Message)
+	}
+
+	assert.NoError(t, spec.Disconnect())
+	spec.findComponent("head/pan").Logger().Info("moved")
+	assert.Len(t, records, 1)
+}
+
+func TestInvalidLogLevel(t *testing.T) {
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        name: robot
+        components:
+          a:
+            log-level: verbose
+     `)))
+	spec, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	err = spec.Resolve()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `a: invalid log level "verbose"`)
+	}
+}
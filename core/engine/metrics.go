@@ -0,0 +1,342 @@
+package engine
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// Metric kinds
+const (
+	MetricCounter   = "counter"
+	MetricGauge     = "gauge"
+	MetricHistogram = "histogram"
+)
+
+// DefaultLatencyBuckets are the upper bounds of histogram buckets in seconds
+var DefaultLatencyBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5}
+
+// MetricsRegistry collects metrics and exposes them in Prometheus text format
+type MetricsRegistry struct {
+	lock     sync.Mutex
+	families map[string]*metricFamily
+}
+
+type metricFamily struct {
+	name   string
+	help   string
+	kind   string
+	series map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labels string
+
+	lock    sync.Mutex
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// NewMetricsRegistry creates a MetricsRegistry
+func NewMetricsRegistry() *MetricsRegistry {
+	return &MetricsRegistry{families: make(map[string]*metricFamily)}
+}
+
+// Counter returns the counter of name with labels in pairs of name and value
+func (r *MetricsRegistry) Counter(name, help string, labels ...string) v0.Counter {
+	return r.series(MetricCounter, name, help, labels)
+}
+
+// Gauge returns the gauge of name with labels in pairs of name and value
+func (r *MetricsRegistry) Gauge(name, help string, labels ...string) v0.Gauge {
+	return r.series(MetricGauge, name, help, labels)
+}
+
+// Histogram returns the histogram of name with labels in pairs of name and value,
+// the buckets are DefaultLatencyBuckets
+func (r *MetricsRegistry) Histogram(name, help string, labels ...string) v0.Histogram {
+	return r.series(MetricHistogram, name, help, labels)
+}
+
+func (r *MetricsRegistry) series(kind, name, help string, labels []string) *metricSeries {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	family := r.families[name]
+	if family == nil {
+		family = &metricFamily{name: name, help: help, kind: kind, series: make(map[string]*metricSeries)}
+		r.families[name] = family
+	} else if family.kind != kind {
+		panic("metric " + name + " is already registered as " + family.kind)
+	}
+	key := formatLabels(labels)
+	s := family.series[key]
+	if s == nil {
+		s = &metricSeries{labels: key}
+		if kind == MetricHistogram {
+			s.buckets = make([]uint64, len(DefaultLatencyBuckets))
+		}
+		family.series[key] = s
+	}
+	return s
+}
+
+// Inc implements v0.Counter
+func (s *metricSeries) Inc() {
+	s.Add(1)
+}
+
+// Add implements v0.Counter and v0.Gauge
+func (s *metricSeries) Add(delta float64) {
+	s.lock.Lock()
+	s.value += delta
+	s.lock.Unlock()
+}
+
+// Set implements v0.Gauge
+func (s *metricSeries) Set(value float64) {
+	s.lock.Lock()
+	s.value = value
+	s.lock.Unlock()
+}
+
+// Observe implements v0.Histogram
+func (s *metricSeries) Observe(value float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for n, bound := range DefaultLatencyBuckets {
+		if value <= bound {
+			s.buckets[n]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// Write writes all metrics in Prometheus text format
+func (r *MetricsRegistry) Write(w io.Writer) error {
+	r.lock.Lock()
+	families := make([]*metricFamily, 0, len(r.families))
+	for _, family := range r.families {
+		families = append(families, family)
+	}
+	r.lock.Unlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	out := bufio.NewWriter(w)
+	for _, family := range families {
+		out.WriteString("# HELP " + family.name + " " + family.help + "\n")
+		out.WriteString("# TYPE " + family.name + " " + family.kind + "\n")
+		r.lock.Lock()
+		series := make([]*metricSeries, 0, len(family.series))
+		for _, s := range family.series {
+			series = append(series, s)
+		}
+		r.lock.Unlock()
+		sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+		for _, s := range series {
+			s.write(out, family)
+		}
+	}
+	return out.Flush()
+}
+
+func (s *metricSeries) write(out *bufio.Writer, family *metricFamily) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if family.kind != MetricHistogram {
+		out.WriteString(family.name + wrapLabels(s.labels) + " " + formatFloat(s.value) + "\n")
+		return
+	}
+	for n, bound := range DefaultLatencyBuckets {
+		out.WriteString(family.name + "_bucket" + wrapLabels(joinLabels(s.labels, `le="`+formatFloat(bound)+`"`)) +
+			" " + strconv.FormatUint(s.buckets[n], 10) + "\n")
+	}
+	out.WriteString(family.name + "_bucket" + wrapLabels(joinLabels(s.labels, `le="+Inf"`)) +
+		" " + strconv.FormatUint(s.count, 10) + "\n")
+	out.WriteString(family.name + "_sum" + wrapLabels(s.labels) + " " + formatFloat(s.value) + "\n")
+	out.WriteString(family.name + "_count" + wrapLabels(s.labels) + " " + strconv.FormatUint(s.count, 10) + "\n")
+}
+
+// ServeHTTP implements http.Handler
+func (r *MetricsRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.Write(w)
+}
+
+func formatLabels(labels []string) string {
+	pairs := make([]string, 0, len(labels)/2)
+	for n := 0; n+1 < len(labels); n += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[n+1])
+		pairs = append(pairs, labels[n]+`="`+value+`"`)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func joinLabels(labels, label string) string {
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func wrapLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// componentMetrics implements v0.Metrics labeled with the component
+type componentMetrics struct {
+	registry *MetricsRegistry
+	labels   []string
+}
+
+func (m *componentMetrics) Counter(name, help string) v0.Counter {
+	if m.registry == nil {
+		return noopMetric{}
+	}
+	return m.registry.Counter(name, help, m.labels...)
+}
+
+func (m *componentMetrics) Gauge(name, help string) v0.Gauge {
+	if m.registry == nil {
+		return noopMetric{}
+	}
+	return m.registry.Gauge(name, help, m.labels...)
+}
+
+func (m *componentMetrics) Histogram(name, help string) v0.Histogram {
+	if m.registry == nil {
+		return noopMetric{}
+	}
+	return m.registry.Histogram(name, help, m.labels...)
+}
+
+type noopMetric struct{}
+
+func (noopMetric) Inc()            {}
+func (noopMetric) Add(float64)     {}
+func (noopMetric) Set(float64)     {}
+func (noopMetric) Observe(float64) {}
+
+// metrics returns the metrics labeled with the component,
+// the metrics do nothing if the spec has no MetricsRegistry
+func (s *ComponentSpec) metrics(labels ...string) v0.Metrics {
+	m := &componentMetrics{labels: append([]string{"component", s.FullID(), "type", s.TypeName}, labels...)}
+	if s.Root != nil {
+		m.registry = s.Root.Metrics
+	}
+	return m
+}
+
+// meteredReactor counts the messages consumed by a reactor endpoint
+type meteredReactor struct {
+	sink     mqhub.MessageSink
+	id       string
+	consumed v0.Counter
+	errors   v0.Counter
+	latency  v0.Histogram
+}
+
+// ID implements mqhub.Endpoint
+func (r *meteredReactor) ID() string {
+	return r.id
+}
+
+// ConsumeMessage implements mqhub.MessageSink
+func (r *meteredReactor) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	start := time.Now()
+	future := r.sink.ConsumeMessage(msg)
+	switch f := future.(type) {
+	case nil:
+		r.observe(start, nil)
+	case *mqhub.ImmediateFuture:
+		r.observe(start, f.Error)
+	default:
+		// observe when completed without blocking the consumer
+		go func() { r.observe(start, f.Wait()) }()
+	}
+	return future
+}
+
+func (r *meteredReactor) observe(start time.Time, err error) {
+	if err != nil {
+		r.errors.Inc()
+	}
+	r.latency.Observe(time.Since(start).Seconds())
+	r.consumed.Inc()
+}
+
+// meteredSink counts the updates of a DataPoint
+type meteredSink struct {
+	sink    mqhub.MessageSink
+	updates v0.Counter
+}
+
+// ConsumeMessage implements mqhub.MessageSink
+func (s *meteredSink) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	s.updates.Inc()
+	return s.sink.ConsumeMessage(msg)
+}
+
+// meterEndpoints wraps the reactors to count the consumed messages
+func (s *ComponentSpec) meterEndpoints(endpoints []mqhub.Endpoint) []mqhub.Endpoint {
+	metered := make([]mqhub.Endpoint, len(endpoints))
+	for n, endpoint := range endpoints {
+		metered[n] = endpoint
+		if _, isDataPoint := endpoint.(*mqhub.DataPoint); isDataPoint {
+			continue
+		}
+		if sink, ok := endpoint.(mqhub.MessageSink); ok {
+			m := s.metrics("endpoint", endpoint.ID())
+			metered[n] = &meteredReactor{
+				sink:     sink,
+				id:       endpoint.ID(),
+				consumed: m.Counter("talk_reactor_messages_total", "Messages consumed by reactors"),
+				errors:   m.Counter("talk_reactor_errors_total", "Messages failed in reactor handlers"),
+				latency:  m.Histogram("talk_reactor_latency_seconds", "Latency of reactor handlers"),
+			}
+		}
+	}
+	return metered
+}
+
+// meterDataPoints counts the updates of the published DataPoints
+func (s *Spec) meterDataPoints() {
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			stateful, ok := comp.Instance.(v0.Stateful)
+			if !ok {
+				continue
+			}
+			for _, endpoint := range stateful.Endpoints() {
+				dp, ok := endpoint.(*mqhub.DataPoint)
+				if !ok || dp.Sink == nil {
+					continue
+				}
+				if _, metered := dp.Sink.(*meteredSink); metered {
+					continue
+				}
+				dp.Sink = &meteredSink{
+					sink: dp.Sink,
+					updates: comp.metrics("endpoint", dp.Name).Counter(
+						"talk_datapoint_updates_total", "Updates published by DataPoints"),
+				}
+			}
+		}
+	}
+}
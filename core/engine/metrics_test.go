@@ -0,0 +1,134 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testMeteredType struct{}
+
+func (t *testMeteredType) Name() string                 { return "test.metered" }
+func (t *testMeteredType) Description() string          { return t.Name() }
+func (t *testMeteredType) Factory() v0.ComponentFactory { return t }
+func (t *testMeteredType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	c := &testMetered{ref: ref, state: &mqhub.DataPoint{Name: "state"}}
+	c.pos = mqhub.ReactorAs("pos", func(pos int) error {
+		if pos < 0 {
+			return fmt.Errorf("invalid pos %d", pos)
+		}
+		c.state.Update(pos)
+		c.moves.Inc()
+		return nil
+	})
+	return c, nil
+}
+
+type testMetered struct {
+	ref   v0.ComponentRef
+	state *mqhub.DataPoint
+	pos   *mqhub.Reactor
+	moves v0.Counter
+}
+
+func (c *testMetered) Ref() v0.ComponentRef         { return c.ref }
+func (c *testMetered) Type() v0.ComponentType       { return &testMeteredType{} }
+func (c *testMetered) Endpoints() []mqhub.Endpoint  { return []mqhub.Endpoint{c.state, c.pos} }
+func (c *testMetered) RegisterMetrics(m v0.Metrics) { c.moves = m.Counter("test_moves_total", "Moves") }
+
+func TestComponentMetrics(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(&testMeteredType{})
+	spec := tester.resolve(`---
+        name: robot
+        components:
+          servo:
+            type: test.metered
+     `)
+	spec.Metrics = NewMetricsRegistry()
+	conn := memhub.NewHub("test").Connector()
+	assert.NoError(t, spec.Connect(conn))
+	pos := conn.Describe("robot").SubComponent("servo").Endpoint("pos")
+	pos.ConsumeMessage(mqhub.MsgFrom(1))
+	pos.ConsumeMessage(mqhub.MsgFrom(2))
+	pos.ConsumeMessage(mqhub.MsgFrom(-1))
+
+	rec := httptest.NewRecorder()
+	spec.Metrics.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	out := rec.Body.String()
+	labels := `component="servo",type="test.metered"`
+	assert.Contains(t, out, "# TYPE talk_reactor_messages_total counter\n")
+	assert.Contains(t, out, `talk_reactor_messages_total{`+labels+`,endpoint="pos"} 3`+"\n")
+	assert.Contains(t, out, `talk_reactor_errors_total{`+labels+`,endpoint="pos"} 1`+"\n")
+	assert.Contains(t, out, "# TYPE talk_reactor_latency_seconds histogram\n")
+	assert.Contains(t, out, `talk_reactor_latency_seconds_bucket{`+labels+`,endpoint="pos",le="+Inf"} 3`+"\n")
+	assert.Contains(t, out, `talk_reactor_latency_seconds_count{`+labels+`,endpoint="pos"} 3`+"\n")
+	assert.Contains(t, out, `talk_datapoint_updates_total{`+labels+`,endpoint="state"} 2`+"\n")
+	assert.Contains(t, out, `test_moves_total{`+labels+`} 2`+"\n")
+	assert.NoError(t, spec.Disconnect())
+}
+
+// testFuture is a MessageSink returning itself as the Future,
+// it completes with the error sent to the channel
+type testFuture chan error
+
+func (f testFuture) Wait() error                                   { return <-f }
+func (f testFuture) ConsumeMessage(msg mqhub.Message) mqhub.Future { return f }
+
+func TestMeteredReactorAsync(t *testing.T) {
+	r := NewMetricsRegistry()
+	future := make(testFuture)
+	reactor := &meteredReactor{
+		sink:     future,
+		consumed: r.Counter("consumed_total", ""),
+		errors:   r.Counter("errors_total", ""),
+		latency:  r.Histogram("latency_seconds", ""),
+	}
+	assert.True(t, reactor.ConsumeMessage(mqhub.MsgFrom(1)) == mqhub.Future(future))
+	var buf bytes.Buffer
+	assert.NoError(t, r.Write(&buf))
+	assert.Contains(t, buf.String(), "consumed_total 0\n")
+
+	future <- fmt.Errorf("failed")
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		buf.Reset()
+		assert.NoError(t, r.Write(&buf))
+		if strings.Contains(buf.String(), "consumed_total 1\n") {
+			break
+		}
+	}
+	assert.Contains(t, buf.String(), "consumed_total 1\n")
+	assert.Contains(t, buf.String(), "errors_total 1\n")
+	assert.Contains(t, buf.String(), "latency_seconds_count 1\n")
+}
+
+func TestMetricsRegistry(t *testing.T) {
+	r := NewMetricsRegistry()
+	r.Counter("requests_total", "Requests", "path", `/a"b`).Add(2)
+	r.Gauge("temperature", "Temperature").Set(36.5)
+	h := r.Histogram("latency_seconds", "Latency")
+	h.Observe(0.003)
+	h.Observe(0.2)
+	var buf bytes.Buffer
+	assert.NoError(t, r.Write(&buf))
+	out := buf.String()
+	assert.Contains(t, out, `requests_total{path="/a\"b"} 2`+"\n")
+	assert.Contains(t, out, "# HELP temperature Temperature\n# TYPE temperature gauge\ntemperature 36.5\n")
+	assert.Contains(t, out, `latency_seconds_bucket{le="0.0025"} 0`+"\n")
+	assert.Contains(t, out, `latency_seconds_bucket{le="0.005"} 1`+"\n")
+	assert.Contains(t, out, `latency_seconds_bucket{le="0.25"} 2`+"\n")
+	assert.Contains(t, out, "latency_seconds_sum 0.203\nlatency_seconds_count 2\n")
+	assert.Panics(t, func() { r.Gauge("requests_total", "") })
+
+	// metrics are no-op without registry
+	spec := &ComponentSpec{LocalID: "a"}
+	spec.metrics().Counter("noop_total", "").Inc()
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Position is the location of a value in the source file
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// String implements fmt.Stringer
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("line %d column %d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// Positions maps the dotted paths of values to the source locations,
+// the items of a list are indexed from 0, e.g. components.cmd.config.args.1
+type Positions map[string]Position
+
+// Sub returns the positions of values under path, relative to path
+func (p Positions) Sub(path string) Positions {
+	prefix := path + "."
+	sub := make(Positions)
+	for key, pos := range p {
+		if strings.HasPrefix(key, prefix) {
+			sub[key[len(prefix):]] = pos
+		}
+	}
+	return sub
+}
+
+// Lookup finds the position of the value at path, or the closest parent
+func (p Positions) Lookup(path string) (Position, bool) {
+	for path != "" {
+		if pos, ok := p[path]; ok {
+			return pos, true
+		}
+		pos := strings.LastIndex(path, ".")
+		if pos < 0 {
+			break
+		}
+		path = path[:pos]
+	}
+	return Position{}, false
+}
+
+func (p Positions) setFile(fn string) {
+	for key, pos := range p {
+		pos.File = fn
+		p[key] = pos
+	}
+}
+
+// maxYAMLDepth stops walking recursive aliases
+const maxYAMLDepth = 256
+
+// yamlPositions locates the keys and list items of YAML, values reached
+// through aliases and merge keys are located at the anchored values
+func yamlPositions(content []byte) Positions {
+	positions := make(Positions)
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &doc); err != nil {
+		// the error is reported by decoding
+		return positions
+	}
+	if doc.Kind == yamlv3.DocumentNode && len(doc.Content) > 0 {
+		nodePositions(positions, "", doc.Content[0], 0)
+	}
+	return positions
+}
+
+func nodePositions(positions Positions, path string, node *yamlv3.Node, depth int) {
+	if node == nil || depth > maxYAMLDepth {
+		return
+	}
+	switch node.Kind {
+	case yamlv3.AliasNode:
+		nodePositions(positions, path, node.Alias, depth+1)
+	case yamlv3.MappingNode:
+		// merged keys are overridden by the keys of the mapping
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Tag == "!!merge" {
+				mergePositions(positions, path, node.Content[i+1], depth+1)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				continue
+			}
+			keyPath := joinPath(path, key.Value)
+			positions.remove(keyPath)
+			positions[keyPath] = nodePosition(key)
+			nodePositions(positions, keyPath, value, depth+1)
+		}
+	case yamlv3.SequenceNode:
+		for n, item := range node.Content {
+			itemPath := joinPath(path, strconv.Itoa(n))
+			positions[itemPath] = nodePosition(item)
+			nodePositions(positions, itemPath, item, depth+1)
+		}
+	}
+}
+
+// mergePositions locates the keys merged by <<, the value is a mapping
+// or a list of mappings where the earlier ones take precedence
+func mergePositions(positions Positions, path string, node *yamlv3.Node, depth int) {
+	if node.Kind == yamlv3.SequenceNode {
+		for n := len(node.Content); n > 0; n-- {
+			nodePositions(positions, path, node.Content[n-1], depth+1)
+		}
+		return
+	}
+	nodePositions(positions, path, node, depth+1)
+}
+
+func nodePosition(node *yamlv3.Node) Position {
+	return Position{Line: node.Line, Column: node.Column}
+}
+
+// remove deletes the positions of path and the values under it
+func (p Positions) remove(path string) {
+	prefix := path + "."
+	for key := range p {
+		if key == path || strings.HasPrefix(key, prefix) {
+			delete(p, key)
+		}
+	}
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
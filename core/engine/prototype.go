@@ -0,0 +1,221 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/easeway/langx.go/mapper"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// PrototypedComponentType is a ComponentType which tells the Go type
+// of the components it creates, so the spec can be checked without
+// creating any component
+type PrototypedComponentType interface {
+	v0.ComponentType
+	// ComponentPrototype returns the type of created components
+	ComponentPrototype() reflect.Type
+}
+
+// ConfigField describes a field mapped from component config
+type ConfigField struct {
+	Key   string
+	Field reflect.StructField
+}
+
+// ConfigFields lists the fields which can be mapped from config
+func ConfigFields(t reflect.Type) (fields []ConfigField) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("map")
+		if key == "-" || f.Tag.Get("inject") != "" {
+			continue
+		}
+		if f.Anonymous && key == "" {
+			fields = append(fields, ConfigFields(f.Type)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if pos := strings.Index(key, ","); pos >= 0 {
+			key = key[:pos]
+		}
+		if key == "" {
+			key = f.Name
+		}
+		fields = append(fields, ConfigField{Key: key, Field: f})
+	}
+	return
+}
+
+// InjectField describes a field accepting injections.
+// The tag is in the form of `inject:"name[,optional]"`.
+// A slice or map field accepts all injections named "name" or
+// "name.key", a map is keyed by "key" or the message path of the
+// injected component. It also accepts a whole collection from a value
+// injection (env, file, param or secret).
+// Other fields accept a single injection.
+// An optional field keeps its original value when nothing is injected
+type InjectField struct {
+	Name     string
+	Optional bool
+	Field    reflect.StructField
+}
+
+// Multiple tells whether the field accepts multiple injections
+func (f *InjectField) Multiple() bool {
+	kind := f.Field.Type.Kind()
+	return kind == reflect.Slice || kind == reflect.Map
+}
+
+// ElemType returns the type of a single injected value
+func (f *InjectField) ElemType() reflect.Type {
+	if f.Multiple() {
+		return f.Field.Type.Elem()
+	}
+	return f.Field.Type
+}
+
+// Accepts tells whether the injection with the name goes into this field,
+// key is the part after "." for a field accepting multiple injections
+func (f *InjectField) Accepts(name string) (key string, ok bool) {
+	if name == f.Name {
+		return "", true
+	}
+	if f.Multiple() && strings.HasPrefix(name, f.Name+".") {
+		return name[len(f.Name)+1:], true
+	}
+	return "", false
+}
+
+func (f *InjectField) inject(fv reflect.Value, key string, value interface{}) error {
+	if ref, ok := value.(v0.ComponentRef); ok {
+		if key == "" {
+			key = ref.MessagePath()
+		}
+		if value = ref.Component(); value == nil {
+			return fmt.Errorf("component %s not created", ref.ComponentID())
+		}
+	}
+	iv := reflect.ValueOf(value)
+	if !iv.IsValid() {
+		return fmt.Errorf("nothing to inject")
+	}
+	if f.Multiple() {
+		// a value from env, file, params or secrets may be the whole collection
+		if iv.Type().AssignableTo(f.Field.Type) {
+			fv.Set(iv)
+			return nil
+		}
+		switch value.(type) {
+		case string:
+			if f.Field.Type.Kind() == reflect.Slice && f.Field.Type.Elem().Kind() == reflect.Uint8 {
+				fv.Set(iv.Convert(f.Field.Type))
+				return nil
+			}
+		case []interface{}, map[string]interface{}:
+			if v, err := mapValue(f.Field.Type, value); err == nil {
+				fv.Set(v)
+				return nil
+			}
+		}
+	}
+	elemType := f.ElemType()
+	if !iv.Type().AssignableTo(elemType) {
+		v, err := mapValue(elemType, value)
+		if err != nil {
+			return fmt.Errorf("type mismatch")
+		}
+		iv = v
+	}
+	switch f.Field.Type.Kind() {
+	case reflect.Slice:
+		fv.Set(reflect.Append(fv, iv))
+	case reflect.Map:
+		if fv.IsNil() {
+			fv.Set(reflect.MakeMap(f.Field.Type))
+		}
+		fv.SetMapIndex(reflect.ValueOf(key), iv)
+	default:
+		fv.Set(iv)
+	}
+	return nil
+}
+
+// mapValue maps a value of env, file, params or secrets to the type
+func mapValue(t reflect.Type, value interface{}) (reflect.Value, error) {
+	v := reflect.New(t)
+	err := mapper.Map(v.Interface(), value)
+	return v.Elem(), err
+}
+
+// InjectFields lists the fields tagged with inject, indexed by injection name
+func InjectFields(t reflect.Type) map[string]*InjectField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := make(map[string]*InjectField)
+	if t.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || f.Anonymous { // unexported or anonymous field
+			continue
+		}
+		tag := f.Tag.Get("inject")
+		if tag == "" || !injectableType(f.Type) {
+			continue
+		}
+		field := &InjectField{Field: f}
+		opts := strings.Split(tag, ",")
+		field.Name = opts[0]
+		for _, opt := range opts[1:] {
+			if opt == "optional" {
+				field.Optional = true
+			}
+		}
+		fields[field.Name] = field
+	}
+	return fields
+}
+
+func injectableType(t reflect.Type) bool {
+	return t.Kind() != reflect.Map || t.Key().Kind() == reflect.String
+}
+
+// findInjectField finds the field accepting the injection
+func findInjectField(fields map[string]*InjectField, name string) (*InjectField, string) {
+	if f := fields[name]; f != nil {
+		return f, ""
+	}
+	if pos := strings.Index(name, "."); pos > 0 {
+		if f := fields[name[:pos]]; f != nil {
+			if key, ok := f.Accepts(name); ok {
+				return f, key
+			}
+		}
+	}
+	return nil, ""
+}
+
+func findConfigField(fields []ConfigField, key string) *ConfigField {
+	for n := range fields {
+		f := &fields[n]
+		if f.Key == key {
+			return f
+		}
+		if f.Field.Tag.Get("map") == "" && strings.EqualFold(f.Key, key) {
+			return f
+		}
+	}
+	return nil
+}
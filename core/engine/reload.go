@@ -0,0 +1,289 @@
+package engine
+
+import (
+	"reflect"
+
+	"github.com/easeway/langx.go/errors"
+)
+
+// Reload resolves the updated spec and applies it to the running one.
+// Components are matched by full ID, and only the ones with changed
+// type, config or injections, the added and removed ones, and all
+// components depending on them are stopped, re-created and re-started.
+// If the changes fail to apply, the previous spec is restored and
+// the stopped components are re-created and re-started
+func (s *Spec) Reload(updated *Spec) error {
+	if err := updated.Resolve(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current := make(map[string]*ComponentSpec)
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			current[comp.FullID()] = comp
+		}
+	}
+	incoming := make(map[string]*ComponentSpec)
+	for _, group := range updated.initOrder {
+		for _, comp := range group {
+			incoming[comp.FullID()] = comp
+		}
+	}
+
+	stale := make(map[string]bool)
+	for id, comp := range current {
+		if spec, exists := incoming[id]; !exists || comp.changedFrom(spec) {
+			for _, dep := range s.dependentsOf(comp) {
+				stale[dep.FullID()] = true
+			}
+		}
+	}
+	for id, comp := range incoming {
+		if spec, exists := current[id]; !exists || comp.changedFrom(spec) {
+			for _, dep := range updated.dependentsOf(comp) {
+				stale[dep.FullID()] = true
+			}
+		}
+	}
+
+	// the merged tree is resolved before anything is stopped,
+	// so an invalid spec leaves the running components untouched
+	prev := s.snapshot()
+	s.Name = updated.Name
+	s.Version = updated.Version
+	s.Description = updated.Description
+	s.Author = updated.Author
+	s.Params = updated.Params
+	s.Secrets = updated.Secrets
+	s.Dir = updated.Dir
+	s.Files = updated.Files
+	s.Types = updated.Types
+	s.positions = updated.positions
+	s.dirs = updated.dirs
+	s.LogLevel = updated.LogLevel
+	s.ChildSpecs = s.merge(updated.ChildSpecs, stale)
+	if err := s.resolveMerged(); err != nil {
+		s.restore(prev)
+		return err
+	}
+
+	var errs errors.AggregatedError
+	for i := len(prev.initOrder); i > 0; i-- {
+		group := prev.initOrder[i-1]
+		for j := len(group); j > 0; j-- {
+			if comp := group[j-1]; stale[comp.FullID()] {
+				s.Logfln("Reload %s", comp.FullID())
+				errs.Add(comp.stop())
+			}
+		}
+	}
+	if err := errs.Aggregate(); err != nil {
+		s.Logfln("Stop components for reload: %v", err)
+	}
+	if s.connector == nil {
+		return nil
+	}
+
+	var created []*ComponentSpec
+	err := s.startPending(func(comp *ComponentSpec) {
+		created = append(created, comp)
+	})
+	if err != nil {
+		s.Logfln("Reload failed, restore previous components: %v", err)
+		for i := len(created); i > 0; i-- {
+			errs.Add(created[i-1].stop())
+		}
+		s.restore(prev)
+		errs.Add(s.startPending(nil))
+		errs.Add(s.republish())
+		if e := errs.Aggregate(); e != nil {
+			s.Logfln("Restore previous components: %v", e)
+		}
+		return err
+	}
+	return s.republish()
+}
+
+// startPending creates and starts (if running) the components
+// without instances in init order, created is called for each
+// component once its instance is created
+func (s *Spec) startPending(created func(*ComponentSpec)) error {
+	var errs errors.AggregatedError
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			if comp.Instance != nil {
+				continue
+			}
+			comp.connect(&errs)
+			if err := errs.Aggregate(); err != nil {
+				return err
+			}
+			if created != nil {
+				created(comp)
+			}
+			if s.running {
+				if err := comp.start(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveMerged resolves the component tree after it's changed
+func (s *Spec) resolveMerged() error {
+	for id, comp := range s.ChildSpecs {
+		comp.init(s, id, nil)
+	}
+	if err := s.Resolve(); err != nil {
+		return err
+	}
+	if s.connector == nil {
+		return nil
+	}
+	var errs errors.AggregatedError
+	for _, spec := range s.ChildSpecs {
+		spec.resolveConnections(s.connector, &errs)
+	}
+	return errs.Aggregate()
+}
+
+// specSnapshot keeps what Reload changes to restore the previous spec
+type specSnapshot struct {
+	name, version, description, author string
+	params                             map[string]interface{}
+	secrets                            string
+	dir                                string
+	files                              []string
+	types                              map[string]*CompositeTypeSpec
+	positions                          Positions
+	dirs                               fileDirs
+	logLevel                           string
+	childSpecs                         map[string]*ComponentSpec
+	initOrder                          [][]*ComponentSpec
+	components                         map[*ComponentSpec]ComponentSpec
+}
+
+func (s *Spec) snapshot() *specSnapshot {
+	snapshot := &specSnapshot{
+		name:        s.Name,
+		version:     s.Version,
+		description: s.Description,
+		author:      s.Author,
+		params:      s.Params,
+		secrets:     s.Secrets,
+		dir:         s.Dir,
+		files:       s.Files,
+		types:       s.Types,
+		positions:   s.positions,
+		dirs:        s.dirs,
+		logLevel:    s.LogLevel,
+		childSpecs:  s.ChildSpecs,
+		initOrder:   s.initOrder,
+		components:  make(map[*ComponentSpec]ComponentSpec),
+	}
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			snapshot.components[comp] = ComponentSpec{
+				ChildSpecs: comp.ChildSpecs,
+				After:      comp.After,
+				Priority:   comp.Priority,
+				Restart:    comp.Restart,
+				Backoff:    comp.Backoff,
+				MaxRetries: comp.MaxRetries,
+				LogLevel:   comp.LogLevel,
+			}
+		}
+	}
+	return snapshot
+}
+
+// restore puts back the previous spec, the stopped components
+// are left without instances
+func (s *Spec) restore(snapshot *specSnapshot) {
+	s.Name = snapshot.name
+	s.Version = snapshot.version
+	s.Description = snapshot.description
+	s.Author = snapshot.author
+	s.Params = snapshot.params
+	s.Secrets = snapshot.secrets
+	s.Dir = snapshot.dir
+	s.Files = snapshot.files
+	s.Types = snapshot.types
+	s.positions = snapshot.positions
+	s.dirs = snapshot.dirs
+	s.LogLevel = snapshot.logLevel
+	s.ChildSpecs = snapshot.childSpecs
+	for comp, saved := range snapshot.components {
+		comp.ChildSpecs = saved.ChildSpecs
+		comp.After = saved.After
+		comp.Priority = saved.Priority
+		comp.Restart = saved.Restart
+		comp.Backoff = saved.Backoff
+		comp.MaxRetries = saved.MaxRetries
+		comp.LogLevel = saved.LogLevel
+	}
+	if err := s.resolveMerged(); err != nil {
+		s.Logfln("Resolve previous spec: %v", err)
+	}
+}
+
+// merge builds the component tree from the updated specs, keeping the
+// running components which are not stale
+func (s *Spec) merge(specs map[string]*ComponentSpec, stale map[string]bool) map[string]*ComponentSpec {
+	merged := make(map[string]*ComponentSpec)
+	for id, spec := range specs {
+		comp := spec
+		if !stale[spec.FullID()] {
+			if running := s.findComponent(spec.FullID()); running != nil {
+				running.After = spec.After
+				running.Priority = spec.Priority
+				running.Restart = spec.Restart
+				running.Backoff = spec.Backoff
+				running.MaxRetries = spec.MaxRetries
+				running.LogLevel = spec.LogLevel
+				comp = running
+			}
+		}
+		comp.ChildSpecs = s.merge(spec.ChildSpecs, stale)
+		merged[id] = comp
+	}
+	return merged
+}
+
+func (s *Spec) findComponent(id string) *ComponentSpec {
+	for _, group := range s.initOrder {
+		for _, comp := range group {
+			if comp.FullID() == id {
+				return comp
+			}
+		}
+	}
+	return nil
+}
+
+// changedFrom tells whether the component must be re-created to
+// apply the other spec, selectors matching a different set of
+// components and changed values of env, files, params or secrets also count
+func (s *ComponentSpec) changedFrom(other *ComponentSpec) bool {
+	return s.TypeName != other.TypeName ||
+		!reflect.DeepEqual(s.Config, other.Config) ||
+		!reflect.DeepEqual(s.InjectSpecs, other.InjectSpecs) ||
+		!reflect.DeepEqual(s.injectedIDs(), other.injectedIDs()) ||
+		!reflect.DeepEqual(s.injectedValues(), other.injectedValues())
+}
+
+// injectedValues returns the values injected from env, file, params or secrets
+func (s *ComponentSpec) injectedValues() map[string]interface{} {
+	values := make(map[string]interface{})
+	for name, inject := range s.InjectSpecs {
+		if inject.IsValue() {
+			values[name] = s.ResolvedInjections[name]
+		}
+	}
+	return values
+}
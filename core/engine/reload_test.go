@@ -0,0 +1,212 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReload(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.spec(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          servo:
+            type: test.crash
+            config:
+              pulse-min: 1
+            inject:
+              dep:
+                type: ref
+                id: root
+          arm:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: servo
+          cam:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+          old:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+     `)
+	assert.NoError(t, spec.Start())
+	root := newSpecTester(t, spec).component("root").Instance
+
+	assert.NoError(t, spec.Reload(tester.resolve(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          servo:
+            type: test.crash
+            config:
+              pulse-min: 2
+            inject:
+              dep:
+                type: ref
+                id: root
+          arm:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: servo
+          cam:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+          extra:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+     `)))
+
+	st := newSpecTester(t, spec)
+	assert.True(t, root == st.component("root").Instance)
+	assert.Len(t, compType.created("cam"), 1)
+	assert.True(t, compType.created("cam")[0].isRunning())
+	servo := compType.created("servo")
+	if assert.Len(t, servo, 2) {
+		assert.False(t, servo[0].isRunning())
+		assert.True(t, servo[1].isRunning())
+		assert.Equal(t, 2, st.component("servo").Config["pulse-min"])
+	}
+	arm := compType.created("arm")
+	if assert.Len(t, arm, 2) {
+		assert.True(t, arm[1].isRunning())
+		assert.True(t, arm[1].Dep == servo[1])
+	}
+	assert.False(t, compType.created("old")[0].isRunning())
+	assert.Nil(t, spec.findComponent("old"))
+	if extra := compType.created("extra"); assert.Len(t, extra, 1) {
+		assert.True(t, extra[0].isRunning())
+	}
+
+	assert.NoError(t, spec.Disconnect())
+	assert.False(t, compType.created("cam")[0].isRunning())
+}
+
+func TestReloadFailureRestores(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.spec(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          servo:
+            type: test.crash
+            config:
+              pulse-min: 1
+            inject:
+              dep:
+                type: ref
+                id: root
+          arm:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: servo
+          old:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+     `)
+	assert.NoError(t, spec.Start())
+
+	// invalid spec stops nothing
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          servo:
+            type: test.crash
+            config:
+              pulse-min: 2
+            inject:
+              dep:
+                type: ref
+                id: missing
+     `)))
+	invalid, err := ParseSpec(conf)
+	assert.NoError(t, err)
+	invalid.TypeResolver = tester.types
+	assert.Error(t, spec.Reload(invalid))
+	assert.Len(t, compType.created("servo"), 1)
+	assert.True(t, compType.created("servo")[0].isRunning())
+	assert.True(t, compType.created("old")[0].isRunning())
+
+	// failed creation restores the previous components
+	assert.Error(t, spec.Reload(tester.resolve(`---
+        name: robot
+        components:
+          root:
+            type: test.A
+          servo:
+            type: test.crash
+            config:
+              pulse-max: 2
+            inject:
+              dep:
+                type: ref
+                id: root
+          arm:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: servo
+          extra:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: root
+     `)))
+	st := newSpecTester(t, spec)
+	servo := compType.created("servo")
+	if assert.Len(t, servo, 2) {
+		assert.False(t, servo[0].isRunning())
+		assert.True(t, servo[1].isRunning())
+		assert.Equal(t, 1, servo[1].PulseMin)
+	}
+	arm := compType.created("arm")
+	if assert.Len(t, arm, 2) {
+		assert.True(t, arm[1].isRunning())
+		assert.True(t, arm[1].Dep == servo[1])
+	}
+	if old := compType.created("old"); assert.Len(t, old, 2) {
+		assert.True(t, old[1].isRunning())
+	}
+	assert.NotNil(t, st.component("old"))
+	assert.Nil(t, spec.findComponent("extra"))
+	for _, extra := range compType.created("extra") {
+		assert.False(t, extra.isRunning())
+	}
+
+	assert.NoError(t, spec.Disconnect())
+}
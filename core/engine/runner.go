@@ -2,50 +2,109 @@ package engine
 
 import (
 	"log"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/easeway/langx.go/errors"
 	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/core/broker"
+	"github.com/robotalks/talk/core/memhub"
 )
 
 // Runner is a simple wrapper to run the engines
 type Runner struct {
 	HubURL    string
 	SpecFile  string
-	Logger    *log.Logger
-	Spec      *Spec
-	Connector mqhub.Connector
+	Overrides []string
+	Profiles  []string
+	Watch     bool
+	LogOutput *LogOutput
+	// Logger receives the text records if LogOutput is nil
+	//
+	// Deprecated: use LogOutput
+	Logger *log.Logger
+	// MetricsAddr is the address serving /metrics, e.g. :9100
+	MetricsAddr string
+	Metrics     *MetricsRegistry
+	// EmbeddedBroker is the address of the in-process MQTT broker, e.g. :1883,
+	// HubURL is replaced to connect to it
+	EmbeddedBroker string
+	Spec           *Spec
+	Connector      mqhub.Connector
+
+	metricsServer *http.Server
+	broker        *broker.Broker
 }
 
-// Start implements LifecycleCtl
-func (r *Runner) Start() error {
-	if r.Spec == nil {
-		raw := NewMapConfig()
-		if err := raw.LoadFile(r.SpecFile); err != nil {
-			return err
+// SpecWatchInterval is the interval polling the spec file for changes
+var SpecWatchInterval = time.Second
+
+func (r *Runner) loadSpec() (*Spec, error) {
+	return LoadSpec(r.SpecFile, &LoadOptions{Profiles: r.Profiles, Overrides: r.Overrides})
+}
+
+// Load loads and resolves the spec file if Spec is not present
+func (r *Runner) Load() error {
+	if r.Spec != nil {
+		return nil
+	}
+	spec, err := r.loadSpec()
+	if err != nil {
+		return err
+	}
+	spec.LogOutput = r.LogOutput
+	spec.Logger = r.Logger
+	if r.MetricsAddr != "" && r.Metrics == nil {
+		r.Metrics = NewMetricsRegistry()
+	}
+	spec.Metrics = r.Metrics
+	if err = spec.Resolve(); err != nil {
+		return err
+	}
+	r.Spec = spec
+	return nil
+}
+
+// Start implements LifecycleCtl, whatever already started
+// is torn down if any step fails
+func (r *Runner) Start() (err error) {
+	if err = r.Load(); err != nil {
+		return err
+	}
+	created := r.Connector == nil
+	defer func() {
+		if err == nil {
+			return
 		}
-		spec, err := ParseSpec(raw)
-		if err != nil {
-			return err
+		if e := r.Stop(); e != nil {
+			r.Spec.Logfln("Teardown error: %v", e)
 		}
-		spec.Logger = r.Logger
-		if err = spec.Resolve(); err != nil {
-			return err
+		if created {
+			r.Connector = nil
 		}
-		r.Spec = spec
+	}()
+	if err = r.startBroker(); err != nil {
+		return err
 	}
 	if r.Connector == nil {
-		conn, err := mqhub.NewConnector(r.HubURL)
+		conn, err := NewConnector(r.HubURL)
 		if err != nil {
 			return err
 		}
 		r.Connector = conn
 	}
-	if err := r.Connector.Connect().Wait(); err != nil {
+	if err = r.Connector.Connect().Wait(); err != nil {
 		return err
 	}
-	if err := r.Spec.Connect(r.Connector); err != nil {
+	if err = r.serveMetrics(); err != nil {
+		return err
+	}
+	if err = r.Spec.Connect(r.Connector); err != nil {
 		return err
 	}
 	return r.Spec.Start()
@@ -55,30 +114,156 @@ func (r *Runner) Start() error {
 func (r *Runner) Stop() error {
 	var errs errors.AggregatedError
 	errs.Add(r.Spec.Disconnect())
-	errs.Add(r.Connector.Close())
+	if r.Connector != nil {
+		errs.Add(r.Connector.Close())
+	}
+	if b := r.broker; b != nil {
+		r.broker = nil
+		errs.Add(b.Close())
+	}
+	if server := r.metricsServer; server != nil {
+		r.metricsServer = nil
+		errs.Add(server.Close())
+	}
 	return errs.Aggregate()
 }
 
-// Run runs the engine
+// startBroker starts the embedded MQTT broker if EmbeddedBroker is set
+func (r *Runner) startBroker() error {
+	if r.EmbeddedBroker == "" || r.broker != nil {
+		return nil
+	}
+	b := broker.New()
+	b.Logger = r.Spec.Log().With("broker", r.EmbeddedBroker)
+	ln, err := b.Listen(r.EmbeddedBroker)
+	if err != nil {
+		return err
+	}
+	r.broker = b
+	r.HubURL = broker.ClientURL(ln.Addr().String())
+	r.Spec.Logfln("Embedded MQTT broker on %s", ln.Addr())
+	return nil
+}
+
+// serveMetrics starts the HTTP server of /metrics if MetricsAddr is set
+func (r *Runner) serveMetrics() error {
+	if r.MetricsAddr == "" || r.metricsServer != nil {
+		return nil
+	}
+	ln, err := net.Listen("tcp", r.MetricsAddr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Metrics)
+	r.metricsServer = &http.Server{Handler: mux}
+	go r.metricsServer.Serve(ln)
+	r.Spec.Logfln("Serve metrics on %s", ln.Addr())
+	return nil
+}
+
+// Reload loads the spec file again and applies the changes
+func (r *Runner) Reload() error {
+	spec, err := r.loadSpec()
+	if err != nil {
+		return err
+	}
+	spec.LogOutput = r.LogOutput
+	spec.Logger = r.Logger
+	spec.TypeResolver = r.Spec.TypeResolver
+	return r.Spec.Reload(spec)
+}
+
+// Run runs the engine, the spec file is reloaded on SIGHUP
+// or when it or any included file is modified if Watch is set
 func (r *Runner) Run() error {
 	if err := r.Start(); err != nil {
 		return err
 	}
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt)
-	<-sigCh
-	return r.Stop()
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+	var changes <-chan time.Time
+	if r.Watch {
+		ticker := time.NewTicker(SpecWatchInterval)
+		defer ticker.Stop()
+		changes = ticker.C
+	}
+	modTimes := r.specModTimes()
+	for {
+		select {
+		case sig := <-sigCh:
+			if sig != syscall.SIGHUP {
+				return r.Stop()
+			}
+		case <-changes:
+			if !r.specModified(modTimes) {
+				continue
+			}
+		}
+		r.Spec.Logfln("Reload %s", r.SpecFile)
+		if err := r.Reload(); err != nil {
+			r.Spec.Logfln("Reload failed: %v", err)
+		}
+		modTimes = r.specModTimes()
+	}
 }
 
-// LogPrefix is default log prefix
-var LogPrefix = "Talk:> "
+// specModTimes returns the modification times of the spec files
+func (r *Runner) specModTimes() map[string]time.Time {
+	files := []string{r.SpecFile}
+	if r.Spec != nil && len(r.Spec.Files) > 0 {
+		files = r.Spec.Files
+	}
+	modTimes := make(map[string]time.Time, len(files))
+	for _, fn := range files {
+		modTimes[fn] = fileModTime(fn)
+	}
+	return modTimes
+}
+
+// specModified determines if any spec file is modified since modTimes
+func (r *Runner) specModified(modTimes map[string]time.Time) bool {
+	for fn, t := range modTimes {
+		if !fileModTime(fn).Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func fileModTime(fn string) time.Time {
+	info, err := os.Stat(fn)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// NewConnector creates mqhub.Connector from URL,
+// mem:// is served by an in-process hub
+func NewConnector(hubURL string) (mqhub.Connector, error) {
+	u, err := url.Parse(hubURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == memhub.Scheme {
+		return memhub.NewConnector(hubURL)
+	}
+	return mqhub.NewConnector(hubURL)
+}
 
 // Run is the simple wrapper to run the engine
 func Run(hubURL, specFile string) error {
-	runner := &Runner{
-		HubURL:   hubURL,
-		SpecFile: specFile,
-		Logger:   log.New(os.Stderr, LogPrefix, log.LstdFlags),
+	return NewRunner(hubURL, specFile).Run()
+}
+
+// NewRunner creates a Runner logging text to stderr
+func NewRunner(hubURL, specFile string) *Runner {
+	output, _ := NewLogOutput(os.Stderr, LogFormatText)
+	return &Runner{
+		HubURL:    hubURL,
+		SpecFile:  specFile,
+		LogOutput: output,
 	}
-	return runner.Run()
 }
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunnerStartTeardown(t *testing.T) {
+	tester := makeTester(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	r := &Runner{
+		Spec:           tester.resolve("---\nname: robot\n"),
+		Connector:      memhub.NewHub("runner").Connector(),
+		EmbeddedBroker: "127.0.0.1:0",
+		MetricsAddr:    ln.Addr().String(),
+		Metrics:        NewMetricsRegistry(),
+	}
+	assert.Error(t, r.Start())
+	assert.Nil(t, r.broker)
+	assert.Nil(t, r.metricsServer)
+	u, err := url.Parse(r.HubURL)
+	assert.NoError(t, err)
+	_, err = net.Dial("tcp", u.Host)
+	assert.Error(t, err)
+
+	r.MetricsAddr = "127.0.0.1:0"
+	assert.NoError(t, r.Start())
+	assert.NotNil(t, r.broker)
+	assert.NotNil(t, r.metricsServer)
+	assert.NoError(t, r.Stop())
+}
+
+func TestRunnerSpecModified(t *testing.T) {
+	dir, err := ioutil.TempDir("", "talk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	base := filepath.Join(dir, "base.yaml")
+	assert.NoError(t, ioutil.WriteFile(base, []byte("---\nname: robot\n"), 0644))
+	fn := filepath.Join(dir, "robot.yaml")
+	assert.NoError(t, ioutil.WriteFile(fn, []byte("---\ninclude: [base.yaml]\n"), 0644))
+
+	spec, err := LoadSpec(fn, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	r := &Runner{SpecFile: fn, Spec: spec}
+	modTimes := r.specModTimes()
+	assert.Len(t, modTimes, 2)
+	assert.False(t, r.specModified(modTimes))
+	later := time.Now().Add(time.Minute)
+	assert.NoError(t, os.Chtimes(base, later, later))
+	assert.True(t, r.specModified(modTimes))
+}
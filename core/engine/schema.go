@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/robotalks/talk/contract/v0"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// ConfigSchema describes the config fields of the prototype
+func ConfigSchema(proto reflect.Type) []*v0.FieldSchema {
+	return (&shaper{}).configFields(proto)
+}
+
+// InjectSchema describes the injection slots of the prototype
+func InjectSchema(proto reflect.Type) []*v0.InjectSchema {
+	fields := InjectFields(proto)
+	injects := make([]*v0.InjectSchema, 0, len(fields))
+	for name, f := range fields {
+		injects = append(injects, &v0.InjectSchema{
+			Name:     name,
+			Type:     f.Field.Type.String(),
+			Hub:      endpointRefType.AssignableTo(f.ElemType()),
+			Optional: f.Optional,
+			Multiple: f.Multiple(),
+		})
+	}
+	sort.Slice(injects, func(i, j int) bool { return injects[i].Name < injects[j].Name })
+	return injects
+}
+
+// PayloadShape describes the shape of message payload encoded as JSON
+func PayloadShape(payload interface{}) *v0.ValueShape {
+	if payload == nil {
+		return nil
+	}
+	return (&shaper{tag: "json"}).shape(reflect.TypeOf(payload))
+}
+
+type shaper struct {
+	tag     string
+	visited map[reflect.Type]bool
+}
+
+func (s *shaper) shape(t reflect.Type) *v0.ValueShape {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s.tag == "json" && t.Name() != "" &&
+		(t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) ||
+			t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return &v0.ValueShape{Kind: v0.KindAny, Type: t.String()}
+	}
+	if s.tag != "json" && reflect.PtrTo(t).Implements(configValueType) {
+		return &v0.ValueShape{Kind: v0.KindAny, Type: t.String()}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &v0.ValueShape{Kind: v0.KindBool}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &v0.ValueShape{Kind: v0.KindInt}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &v0.ValueShape{Kind: v0.KindUint}
+	case reflect.Float32, reflect.Float64:
+		return &v0.ValueShape{Kind: v0.KindFloat}
+	case reflect.String:
+		return &v0.ValueShape{Kind: v0.KindString}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &v0.ValueShape{Kind: v0.KindBytes}
+		}
+		return &v0.ValueShape{Kind: v0.KindArray, Elem: s.shape(t.Elem())}
+	case reflect.Map:
+		return &v0.ValueShape{Kind: v0.KindMap, Elem: s.shape(t.Elem())}
+	case reflect.Struct:
+		if s.visited[t] {
+			return &v0.ValueShape{Kind: v0.KindAny, Type: t.String()}
+		}
+		if s.visited == nil {
+			s.visited = make(map[reflect.Type]bool)
+		}
+		s.visited[t] = true
+		defer delete(s.visited, t)
+		var fields []*v0.FieldSchema
+		if s.tag == "json" {
+			fields = s.jsonFields(t)
+		} else {
+			fields = s.configFields(t)
+		}
+		return &v0.ValueShape{Kind: v0.KindObject, Fields: fields}
+	}
+	return &v0.ValueShape{Kind: v0.KindAny}
+}
+
+func (s *shaper) configFields(t reflect.Type) []*v0.FieldSchema {
+	configFields := ConfigFields(t)
+	fields := make([]*v0.FieldSchema, 0, len(configFields))
+	for _, f := range configFields {
+		fields = append(fields, &v0.FieldSchema{
+			Name:     f.Key,
+			Shape:    s.shape(f.Field.Type),
+			Optional: f.Field.Type.Kind() == reflect.Ptr,
+		})
+	}
+	return fields
+}
+
+func (s *shaper) jsonFields(t reflect.Type) (fields []*v0.FieldSchema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		if f.Anonymous && opts[0] == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, s.jsonFields(ft)...)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		field := &v0.FieldSchema{Name: opts[0], Shape: s.shape(f.Type)}
+		if field.Name == "" {
+			field.Name = f.Name
+		}
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				field.Optional = true
+			}
+		}
+		fields = append(fields, field)
+	}
+	return
+}
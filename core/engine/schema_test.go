@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSchemaConfig struct {
+	Pin   string   `map:"pin"`
+	Limit *float32 `map:"limit"`
+}
+
+type testSchemaState struct {
+	On    bool  `json:"on"`
+	Level *byte `json:"level,omitempty"`
+	ID    int   `json:"-"`
+}
+
+type testSchemaComponent struct {
+	testSchemaConfig
+	Casts map[string]string `map:"cast"`
+	Ctl   v0.LifecycleCtl   `inject:"ctl" map:"-"`
+	ref   v0.ComponentRef
+}
+
+func (c *testSchemaComponent) Ref() v0.ComponentRef   { return c.ref }
+func (c *testSchemaComponent) Type() v0.ComponentType { return nil }
+
+func TestComponentSchema(t *testing.T) {
+	typ := DefineComponentType("test.schema", nil).
+		Prototype(&testSchemaComponent{}).
+		DataPoint("state", testSchemaState{}).
+		Reactor("on", false).
+		Reactor("raw", nil)
+	schema := typ.Schema()
+
+	if assert.Len(t, schema.Config, 3) {
+		assert.Equal(t, "pin", schema.Config[0].Name)
+		assert.Equal(t, "string", schema.Config[0].Shape.String())
+		assert.Equal(t, "limit", schema.Config[1].Name)
+		assert.True(t, schema.Config[1].Optional)
+		assert.Equal(t, "map[string]string", schema.Config[2].Shape.String())
+	}
+	if assert.Len(t, schema.Injects, 1) {
+		assert.Equal(t, "ctl", schema.Injects[0].Name)
+		assert.Equal(t, "v0.LifecycleCtl", schema.Injects[0].Type)
+		assert.False(t, schema.Injects[0].Hub)
+	}
+	if assert.Len(t, schema.Endpoints, 3) {
+		assert.Equal(t, v0.EndpointDataPoint, schema.Endpoints[0].Direction)
+		assert.Equal(t, "{on: bool, level?: uint}", schema.Endpoints[0].Payload.String())
+		assert.Equal(t, v0.EndpointReactor, schema.Endpoints[1].Direction)
+		assert.Equal(t, "bool", schema.Endpoints[1].Payload.String())
+		assert.Nil(t, schema.Endpoints[2].Payload)
+	}
+}
@@ -0,0 +1,142 @@
+package engine
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// InjectionList is the collection of components or endpoints
+// selected by a single injection
+type InjectionList []interface{}
+
+// Multiple tells whether the injection selects a collection
+func (s *InjectionSpec) Multiple() bool {
+	return len(s.IDs) > 0 || s.Select != "" || s.ComponentType != "" || len(s.Paths) > 0
+}
+
+// resolveRefs resolves the components selected by a ref injection
+func (s *ComponentSpec) resolveRefs(inject *InjectionSpec) ([]*ComponentSpec, error) {
+	var refs []*ComponentSpec
+	if inject.ID != "" {
+		spec := s.resolveIDRef(inject.ID)
+		if spec == nil {
+			return nil, fmt.Errorf("unresolved inject %s", inject.ID)
+		}
+		refs = append(refs, spec)
+	}
+	for _, id := range inject.IDs {
+		spec := s.resolveIDRef(id)
+		if spec == nil {
+			return nil, fmt.Errorf("unresolved inject %s", id)
+		}
+		refs = append(refs, spec)
+	}
+	if inject.Select != "" {
+		refs = append(refs, s.selectRefs(inject.Select)...)
+	} else if inject.ComponentType != "" && inject.ID == "" && len(inject.IDs) == 0 {
+		for _, spec := range s.Root.ChildSpecs {
+			refs = append(refs, spec.descendants()...)
+		}
+	}
+
+	selected := make([]*ComponentSpec, 0, len(refs))
+	visited := make(map[*ComponentSpec]bool)
+	for _, spec := range refs {
+		if visited[spec] || spec == s {
+			continue
+		}
+		visited[spec] = true
+		if inject.ComponentType != "" {
+			if matched, _ := path.Match(inject.ComponentType, spec.TypeName); !matched {
+				continue
+			}
+		}
+		selected = append(selected, spec)
+	}
+	if inject.Select != "" || inject.ComponentType != "" {
+		sortSpecs(selected)
+	}
+	return selected, nil
+}
+
+// selectRefs resolves a glob pattern, e.g. legs/*/servo, in the same
+// way as resolveIDRef, each segment is matched using path.Match
+func (s *ComponentSpec) selectRefs(pattern string) []*ComponentSpec {
+	type scope struct {
+		spec       *ComponentSpec
+		components map[string]*ComponentSpec
+	}
+	current := []scope{{spec: s.ParentSpec, components: s.Root.ChildSpecs}}
+	if s.ParentSpec != nil {
+		current[0].components = s.ParentSpec.ChildSpecs
+	}
+	if strings.HasPrefix(pattern, "/") {
+		current = []scope{{components: s.Root.ChildSpecs}}
+		pattern = pattern[1:]
+	}
+	for _, seg := range strings.Split(pattern, "/") {
+		var next []scope
+		for _, sc := range current {
+			if seg == ".." {
+				if sc.spec == nil {
+					continue
+				}
+				parent := sc.spec.ParentSpec
+				if parent == nil {
+					next = append(next, scope{components: s.Root.ChildSpecs})
+				} else {
+					next = append(next, scope{spec: parent, components: parent.ChildSpecs})
+				}
+				continue
+			}
+			for _, id := range sortedKeys(sc.components) {
+				if matched, _ := path.Match(seg, id); matched {
+					spec := sc.components[id]
+					next = append(next, scope{spec: spec, components: spec.ChildSpecs})
+				}
+			}
+		}
+		current = next
+	}
+	var selected []*ComponentSpec
+	for _, sc := range current {
+		if sc.spec != nil {
+			selected = append(selected, sc.spec)
+		}
+	}
+	return selected
+}
+
+func (s *ComponentSpec) descendants() []*ComponentSpec {
+	specs := []*ComponentSpec{s}
+	for _, spec := range s.ChildSpecs {
+		specs = append(specs, spec.descendants()...)
+	}
+	return specs
+}
+
+// injectedIDs returns the full IDs of components injected by ref
+func (s *ComponentSpec) injectedIDs() map[string][]string {
+	ids := make(map[string][]string)
+	for name, injection := range s.ResolvedInjections {
+		switch v := injection.(type) {
+		case *ComponentSpec:
+			ids[name] = []string{v.FullID()}
+		case InjectionList:
+			for _, item := range v {
+				if spec, ok := item.(*ComponentSpec); ok {
+					ids[name] = append(ids[name], spec.FullID())
+				}
+			}
+		}
+	}
+	return ids
+}
+
+func sortSpecs(specs []*ComponentSpec) {
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].FullID() < specs[j].FullID()
+	})
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testGroupType struct{}
+
+func (t *testGroupType) Name() string                 { return "test.group" }
+func (t *testGroupType) Description() string          { return t.Name() }
+func (t *testGroupType) Factory() v0.ComponentFactory { return t }
+func (t *testGroupType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	inst := &testGroup{ref: ref}
+	return inst, SetupComponent(inst, ref)
+}
+
+type testGroup struct {
+	Members map[string]v0.LifecycleCtl `inject:"members"`
+	Remotes []mqhub.EndpointRef        `inject:"remotes,optional"`
+	ref     v0.ComponentRef
+}
+
+func (g *testGroup) Ref() v0.ComponentRef   { return g.ref }
+func (g *testGroup) Type() v0.ComponentType { return &testGroupType{} }
+
+func TestSelectorInjection(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeInstanceA, &testGroupType{})
+	spec := tester.spec(`---
+        name: robot
+        components:
+          legs:
+            components:
+              l1:
+                components:
+                  servo:
+                    type: test.A
+                  knee:
+                    type: test.A
+              l2:
+                components:
+                  servo:
+                    type: test.A
+          led1:
+            type: test.A
+          gait:
+            type: test.group
+            inject:
+              members:
+                type: ref
+                select: legs/*/servo
+              remotes:
+                type: hub
+                paths:
+                  - remote/a/ep
+                  - remote/b/ep
+          listed:
+            type: test.group
+            inject:
+              members:
+                type: ref
+                ids:
+                  - led1
+                  - legs/l1/knee
+          all:
+            type: test.group
+            inject:
+              members:
+                type: ref
+                component-type: test.A
+          filtered:
+            type: test.group
+            inject:
+              members:
+                type: ref
+                select: legs/l1/*
+                component-type: test.A
+     `)
+	specT := newSpecTester(t, spec)
+	inst := func(id string) v0.LifecycleCtl {
+		return spec.findComponent(id).Instance.(v0.LifecycleCtl)
+	}
+	gait := specT.component("gait").Instance.(*testGroup)
+	assert.Equal(t, map[string]v0.LifecycleCtl{
+		"legs/l1/servo": inst("legs/l1/servo"),
+		"legs/l2/servo": inst("legs/l2/servo"),
+	}, gait.Members)
+	assert.Len(t, gait.Remotes, 2)
+	listed := specT.component("listed").Instance.(*testGroup)
+	assert.Equal(t, map[string]v0.LifecycleCtl{
+		"led1":         inst("led1"),
+		"legs/l1/knee": inst("legs/l1/knee"),
+	}, listed.Members)
+	assert.Len(t, specT.component("all").Instance.(*testGroup).Members, 4)
+	assert.Len(t, specT.component("filtered").Instance.(*testGroup).Members, 2)
+	assert.NoError(t, spec.Validate())
+	assert.NoError(t, spec.Disconnect())
+}
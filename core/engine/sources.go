@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// IsValue tells whether the injection is a value instead of a component
+// or an endpoint, e.g. from environment variables, files, params or secrets
+func (s *InjectionSpec) IsValue() bool {
+	switch s.Type {
+	case InjectEnv, InjectFile, InjectParam, InjectSecret:
+		return true
+	}
+	return false
+}
+
+// resolveValue resolves an injection from env, file, param or secret
+func (s *ComponentSpec) resolveValue(name string, inject *InjectionSpec) (interface{}, error) {
+	var value interface{}
+	var found bool
+	switch inject.Type {
+	case InjectFile:
+		if inject.Path == "" {
+			return nil, fmt.Errorf("'path' required")
+		}
+		path := s.specPath() + ".inject." + name + ".path"
+		content, err := ioutil.ReadFile(s.Root.filePathAt(path, inject.Path))
+		if err != nil {
+			return nil, err
+		}
+		return string(content), nil
+	case InjectEnv:
+		value, found = os.LookupEnv(inject.Name)
+	case InjectParam:
+		value, found = s.Root.Params[inject.Name]
+	case InjectSecret:
+		value, found = s.Root.secrets[inject.Name]
+	}
+	if inject.Name == "" {
+		return nil, fmt.Errorf("'name' required")
+	}
+	if !found {
+		if inject.Default == nil {
+			return nil, fmt.Errorf("%s %s not defined", inject.Type, inject.Name)
+		}
+		value = inject.Default
+	}
+	return value, nil
+}
+
+// loadSecrets loads the credentials file specified by secrets
+func (s *Spec) loadSecrets() error {
+	s.secrets = nil
+	if s.Secrets == "" {
+		return nil
+	}
+	conf := NewMapConfig()
+	if err := conf.LoadFile(s.filePathAt("secrets", s.Secrets)); err != nil {
+		return fmt.Errorf("load secrets: %v", err)
+	}
+	s.secrets = conf.Map
+	return nil
+}
+
+func (s *Spec) filePath(fn string) string {
+	if s.Dir == "" || filepath.IsAbs(fn) {
+		return fn
+	}
+	return filepath.Join(s.Dir, fn)
+}
+
+// filePathAt resolves fn relative to the directory of the spec file
+// declaring the value at path, or Dir if the file is unknown
+func (s *Spec) filePathAt(path, fn string) string {
+	if dir, ok := s.dirs[path]; ok && !filepath.IsAbs(fn) {
+		return filepath.Join(dir, fn)
+	}
+	return s.filePath(fn)
+}
+
+// FilePath resolves fn, the value of config key of ref, relative to the
+// directory of the spec file declaring it the same as file injections,
+// fn is returned as is if ref isn't from a spec
+func FilePath(ref v0.ComponentRef, key, fn string) string {
+	if spec, ok := ref.(*ComponentSpec); ok && spec.Root != nil {
+		return spec.Root.filePathAt(spec.specPath()+".config."+key, fn)
+	}
+	return fn
+}
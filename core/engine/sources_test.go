@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSourcesType struct{}
+
+func (t *testSourcesType) Name() string                 { return "test.sources" }
+func (t *testSourcesType) Description() string          { return t.Name() }
+func (t *testSourcesType) Factory() v0.ComponentFactory { return t }
+func (t *testSourcesType) ComponentPrototype() reflect.Type {
+	return reflect.TypeOf(&testSources{})
+}
+func (t *testSourcesType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	inst := &testSources{ref: ref}
+	return inst, SetupComponent(inst, ref)
+}
+
+type testSources struct {
+	Port    string            `map:"port"`
+	Rate    int               `map:"rate"`
+	Token   string            `inject:"token"`
+	Cert    []byte            `inject:"cert"`
+	Home    string            `inject:"home"`
+	Servos  map[string]int    `inject:"servos"`
+	Unused  string            `inject:"unused,optional"`
+	Aliases map[string]string `inject:"aliases,optional"`
+	ref     v0.ComponentRef
+}
+
+func (s *testSources) Ref() v0.ComponentRef   { return s.ref }
+func (s *testSources) Type() v0.ComponentType { return &testSourcesType{} }
+
+func TestInjectionSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "talk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	specFile := filepath.Join(dir, "spec.yaml")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cert.pem"), []byte("CERT"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "creds.yaml"), []byte("api-token: secret\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(specFile, []byte(`---
+name: robot
+secrets: creds.yaml
+params:
+  serial: /dev/ttyUSB0
+  servos:
+    pan: 1
+    tilt: 2
+components:
+  c:
+    type: test.sources
+    config:
+      rate: 10
+    inject:
+      port:
+        type: param
+        name: serial
+      token:
+        type: secret
+        name: api-token
+      cert:
+        type: file
+        path: cert.pem
+      home:
+        type: env
+        name: TALK_TEST_HOME
+        default: /home/talk
+      servos:
+        type: param
+        name: servos
+`), 0644))
+	os.Unsetenv("TALK_TEST_HOME")
+
+	tester := makeTester(t)
+	tester.addTypes(&testSourcesType{})
+	spec, err := LoadSpecFile(specFile)
+	assert.NoError(t, err)
+	spec.TypeResolver = tester.types
+	assert.NoError(t, spec.Resolve())
+	assert.NoError(t, spec.Validate())
+	assert.NoError(t, spec.Connect(tester))
+	c := newSpecTester(t, spec).component("c").Instance.(*testSources)
+	assert.Equal(t, "/dev/ttyUSB0", c.Port)
+	assert.Equal(t, 10, c.Rate)
+	assert.Equal(t, "secret", c.Token)
+	assert.Equal(t, []byte("CERT"), c.Cert)
+	assert.Equal(t, "/home/talk", c.Home)
+	assert.Equal(t, map[string]int{"pan": 1, "tilt": 2}, c.Servos)
+	assert.Equal(t, filepath.Join(dir, "frames"), FilePath(c.ref, "dir", "frames"))
+	assert.Equal(t, "/tmp/frames", FilePath(c.ref, "dir", "/tmp/frames"))
+	assert.Equal(t, "frames", FilePath(nil, "dir", "frames"))
+	assert.NoError(t, spec.Disconnect())
+
+	spec = tester.resolve(`---
+        name: robot
+        components:
+          c:
+            type: test.sources
+     `)
+	spec.ChildSpecs["c"].InjectSpecs["token"] = &InjectionSpec{Type: InjectEnv, Name: "TALK_TEST_UNDEFINED"}
+	assert.Error(t, spec.Resolve())
+}
+
+func TestIncludedFilePaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "talk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	write := func(fn, content string) string {
+		fn = filepath.Join(dir, fn)
+		assert.NoError(t, ioutil.WriteFile(fn, []byte(content), 0644))
+		return fn
+	}
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "common"), 0755))
+	write("common/cert.pem", "CERT")
+	write("common/creds.yaml", "api-token: secret\n")
+	write("common/base.yaml", `---
+name: robot
+secrets: creds.yaml
+params:
+  servos:
+    pan: 1
+components:
+  c:
+    type: test.sources
+    inject:
+      token:
+        type: secret
+        name: api-token
+      cert:
+        type: file
+        path: cert.pem
+      home:
+        type: env
+        name: TALK_TEST_HOME
+        default: /home/talk
+      servos:
+        type: param
+        name: servos
+  cam:
+    type: test.cam
+    config:
+      dir: frames
+profiles:
+  replay:
+    overlay:
+      cam:
+        config:
+          file: replay.mjpg
+`)
+	specFile := write("robot.yaml", `---
+include:
+  - common/base.yaml
+components:
+  c:
+    config:
+      rate: 10
+overlay:
+  cam:
+    config:
+      file: clip.mjpg
+`)
+
+	spec, err := LoadSpec(specFile, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	cam := spec.ChildSpecs["cam"]
+	assert.Equal(t, filepath.Join(dir, "common", "frames"), FilePath(cam, "dir", "frames"))
+	assert.Equal(t, filepath.Join(dir, "clip.mjpg"), FilePath(cam, "file", "clip.mjpg"))
+	delete(spec.ChildSpecs, "cam")
+
+	tester := makeTester(t)
+	tester.addTypes(&testSourcesType{})
+	spec.TypeResolver = tester.types
+	assert.NoError(t, spec.Resolve())
+	assert.NoError(t, spec.Connect(tester))
+	c := newSpecTester(t, spec).component("c").Instance.(*testSources)
+	assert.Equal(t, "secret", c.Token)
+	assert.Equal(t, []byte("CERT"), c.Cert)
+	assert.Equal(t, 10, c.Rate)
+	assert.NoError(t, spec.Disconnect())
+
+	// overrides are relative to the spec file
+	spec, err = LoadSpec(specFile, &LoadOptions{Overrides: []string{"components.cam.config.dir=frames"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, filepath.Join(dir, "frames"), FilePath(spec.ChildSpecs["cam"], "dir", "frames"))
+	}
+
+	spec, err = LoadSpec(specFile, &LoadOptions{Profiles: []string{"replay"}})
+	if assert.NoError(t, err) {
+		cam := spec.ChildSpecs["cam"]
+		assert.Equal(t, "replay.mjpg", cam.Config["file"])
+		assert.Equal(t, filepath.Join(dir, "common", "replay.mjpg"), FilePath(cam, "file", "replay.mjpg"))
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"log"
 	"path"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/easeway/langx.go/errors"
 	"github.com/robotalks/mqhub.go/mqhub"
@@ -13,30 +16,65 @@ import (
 
 // Spec is the top-level document
 type Spec struct {
-	Name        string                    `map:"name"`
-	Version     string                    `map:"version"`
-	Description string                    `map:"description"`
-	Author      string                    `map:"author"`
-	ChildSpecs  map[string]*ComponentSpec `map:"components"`
+	Name        string                        `map:"name"`
+	Version     string                        `map:"version"`
+	Description string                        `map:"description"`
+	Author      string                        `map:"author"`
+	ChildSpecs  map[string]*ComponentSpec     `map:"components"`
+	Params      map[string]interface{}        `map:"params"`
+	Secrets     string                        `map:"secrets"` // path to credentials file
+	Types       map[string]*CompositeTypeSpec `map:"types"`
+	LogLevel    string                        `map:"log-level"` // default log level of components
 
 	TypeResolver v0.ComponentTypeResolver `map:"-"`
-	Logger       *log.Logger              `map:"-"`
+	LogOutput    *LogOutput               `map:"-"`
+	Metrics      *MetricsRegistry         `map:"-"`
+	// Logger receives the text records if LogOutput is nil
+	//
+	// Deprecated: use LogOutput
+	Logger *log.Logger `map:"-"`
+	// Dir is the base for relative file paths, it's the directory of spec file
+	Dir string `map:"-"`
+	// Files are the spec files read by LoadSpec, including the included ones
+	Files []string `map:"-"`
 
 	initOrder   [][]*ComponentSpec
+	positions   Positions
+	dirs        fileDirs
+	secrets     map[string]interface{}
+	engine      *engineComponent
+	connector   mqhub.Connector
 	publication mqhub.Publication
+	running     bool
+	lock        sync.Mutex
+	logLock     sync.Mutex
+	logStream   *mqhub.DataPoint
 }
 
 // Injection Types
 const (
-	InjectRef = "ref"
-	InjectHub = "hub"
+	InjectRef    = "ref"
+	InjectHub    = "hub"
+	InjectEnv    = "env"
+	InjectFile   = "file"
+	InjectParam  = "param"
+	InjectSecret = "secret"
 )
 
-// InjectionSpec defines an injection
+// InjectionSpec defines an injection.
+// A ref injection with ids, select or component-type injects
+// the collection of selected components, and a hub injection
+// with paths injects the collection of endpoints
 type InjectionSpec struct {
-	Type string `map:"type"`
-	ID   string `map:"id"`   // when type is ref
-	Path string `map:"path"` // when type is hub
+	Type          string      `map:"type" json:"type"`
+	ID            string      `map:"id" json:"id,omitempty"`                         // when type is ref
+	IDs           []string    `map:"ids" json:"ids,omitempty"`                       // when type is ref
+	Select        string      `map:"select" json:"select,omitempty"`                 // when type is ref, glob of IDs
+	ComponentType string      `map:"component-type" json:"component-type,omitempty"` // when type is ref, glob of type names
+	Path          string      `map:"path" json:"path,omitempty"`                     // when type is hub or file
+	Paths         []string    `map:"paths" json:"paths,omitempty"`                   // when type is hub
+	Name          string      `map:"name" json:"name,omitempty"`                     // when type is env, param or secret
+	Default       interface{} `map:"default" json:"-"`                               // when type is env, param or secret
 }
 
 // ComponentSpec defines a specific component
@@ -46,6 +84,11 @@ type ComponentSpec struct {
 	ChildSpecs  map[string]*ComponentSpec `map:"components"`
 	Config      map[string]interface{}    `map:"config"`
 	After       []string                  `map:"after"`
+	Priority    int                       `map:"priority"`
+	Restart     string                    `map:"restart"`
+	Backoff     Duration                  `map:"backoff"`
+	MaxRetries  int                       `map:"max-retries"`
+	LogLevel    string                    `map:"log-level"`
 
 	LocalID            string                 `map:"-"`
 	Root               *Spec                  `map:"-"`
@@ -57,13 +100,22 @@ type ComponentSpec struct {
 	depends   map[string]*ComponentSpec
 	activates map[string]*ComponentSpec
 
-	started bool
+	expanded  bool
+	started   bool
+	startedAt time.Time
+	retries   int
+	state     string
+	lastErr   error
 }
 
 // ParseSpec parses spec from a config
 func ParseSpec(input Config) (*Spec, error) {
 	var spec Spec
 	err := input.As(&spec)
+	if conf, ok := input.(*MapConfig); ok {
+		spec.positions = conf.Positions
+		spec.dirs = conf.dirs
+	}
 	if err == nil && spec.ChildSpecs != nil {
 		for id, s := range spec.ChildSpecs {
 			s.init(&spec, id, nil)
@@ -72,6 +124,12 @@ func ParseSpec(input Config) (*Spec, error) {
 	return &spec, err
 }
 
+// LoadSpecFile loads and parses spec from JSON/YAML file,
+// overrides in the form of path=value are applied before parsing
+func LoadSpecFile(fn string, overrides ...string) (*Spec, error) {
+	return LoadSpec(fn, &LoadOptions{Overrides: overrides})
+}
+
 // ID implements mqhub.Identifier
 func (s *Spec) ID() string {
 	return s.Name
@@ -87,27 +145,52 @@ func (s *Spec) Components() (comps []mqhub.Component) {
 	for _, spec := range s.ChildSpecs {
 		comps = append(comps, spec)
 	}
+	if s.engine != nil {
+		comps = append(comps, s.engine)
+	}
 	return
 }
 
-// Resolve constructs the component instances
+// Resolve constructs the component instances.
+// Components are arranged in init groups, a component is placed in
+// the group right after the last group containing its dependencies.
+// Inside a group, components with higher priority go first, and
+// components with the same priority are ordered by full ID
 func (s *Spec) Resolve() error {
+	if _, exists := s.ChildSpecs[EngineComponentID]; exists {
+		return fmt.Errorf("component ID %s is reserved", EngineComponentID)
+	}
+	if err := s.loadSecrets(); err != nil {
+		return err
+	}
+	resolver, err := s.typeResolver()
+	if err != nil {
+		return err
+	}
+	for _, id := range sortedKeys(s.ChildSpecs) {
+		if err := s.ChildSpecs[id].expand(resolver, 0); err != nil {
+			return err
+		}
+	}
 	all := make(map[string]*ComponentSpec)
 	for _, spec := range s.ChildSpecs {
 		spec.resolveStart(all)
 	}
 	errs := &errors.AggregatedError{}
+	if s.LogLevel != "" {
+		if _, err := v0.ParseLogLevel(s.LogLevel); err != nil {
+			errs.Add(err)
+		}
+	}
 	for _, spec := range s.ChildSpecs {
 		spec.buildDependencies(errs)
+		spec.checkRestartPolicy(errs)
+		spec.checkLogLevel(errs)
 	}
 	if err := errs.Aggregate(); err != nil {
 		return err
 	}
 
-	resolver := s.TypeResolver
-	if resolver == nil {
-		resolver = v0.DefaultComponentTypeRegistry
-	}
 	for _, spec := range s.ChildSpecs {
 		spec.resolveType(resolver, errs)
 	}
@@ -115,21 +198,27 @@ func (s *Spec) Resolve() error {
 		return err
 	}
 
+	s.initOrder = nil
 	for len(all) > 0 {
-		comps := make([]*ComponentSpec, 0, len(all))
+		var ready []*ComponentSpec
 		for _, comp := range all {
-			comps = append(comps, comp)
-		}
-		ready := make([]*ComponentSpec, 0, len(all))
-		for _, comp := range comps {
-			ready = append(ready, comp.activate(all)...)
+			if len(comp.depends) == 0 {
+				ready = append(ready, comp)
+			}
 		}
 		if len(ready) == 0 {
-			for _, comp := range comps {
-				errs.Add(fmt.Errorf("cyclic injection in %s", comp.FullID()))
+			for _, id := range sortedKeys(all) {
+				errs.Add(fmt.Errorf("cyclic injection in %s", id))
 			}
 			break
 		}
+		sortInitGroup(ready)
+		for _, comp := range ready {
+			delete(all, comp.FullID())
+			for _, spec := range comp.activates {
+				delete(spec.depends, comp.FullID())
+			}
+		}
 		s.initOrder = append(s.initOrder, ready)
 	}
 	return errs.Aggregate()
@@ -153,26 +242,72 @@ func (s *Spec) Connect(connector mqhub.Connector) error {
 		return err
 	}
 
-	pub, err := connector.Publish(s)
-	if err == nil {
-		s.publication = pub
+	s.connector = connector
+	return s.republish()
+}
+
+// StartError reports the component failed to start and
+// the components stopped as rollback
+type StartError struct {
+	ComponentID string
+	Err         error
+	Stopped     []string
+	StopErr     error
+}
+
+// Error implements error
+func (e *StartError) Error() string {
+	msg := fmt.Sprintf("start %s failed: %v", e.ComponentID, e.Err)
+	if len(e.Stopped) > 0 {
+		msg += "; rolled back " + strings.Join(e.Stopped, ", ")
 	}
-	return err
+	if e.StopErr != nil {
+		msg += fmt.Sprintf("; rollback error: %v", e.StopErr)
+	}
+	return msg
 }
 
-// Start starts all LifecycleCtl components
+// Start starts all LifecycleCtl components.
+// It stops at the first failure and stops the already started
+// components in reverse order, the failure is returned as *StartError
 func (s *Spec) Start() error {
-	var errs errors.AggregatedError
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.running = true
+	var started []*ComponentSpec
 	for _, group := range s.initOrder {
 		for _, comp := range group {
-			comp.start(&errs)
+			if err := comp.start(); err != nil {
+				return s.rollback(comp, err, started)
+			}
+			if comp.started {
+				started = append(started, comp)
+			}
 		}
 	}
-	return errs.Aggregate()
+	return nil
+}
+
+func (s *Spec) rollback(failed *ComponentSpec, err error, started []*ComponentSpec) error {
+	startErr := &StartError{ComponentID: failed.FullID(), Err: err}
+	s.Logfln("Start %s failed: %v", startErr.ComponentID, err)
+	var errs errors.AggregatedError
+	for i := len(started); i > 0; i-- {
+		comp := started[i-1]
+		errs.Add(comp.halt())
+		startErr.Stopped = append(startErr.Stopped, comp.FullID())
+	}
+	startErr.StopErr = errs.Aggregate()
+	s.running = false
+	s.Logfln("%v", startErr)
+	return startErr
 }
 
 // Disconnect tears off the components from mqhub
 func (s *Spec) Disconnect() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.running = false
 	var errs errors.AggregatedError
 	for i := len(s.initOrder); i > 0; i-- {
 		group := s.initOrder[i-1]
@@ -183,23 +318,20 @@ func (s *Spec) Disconnect() error {
 	pub := s.publication
 	s.publication = nil
 	if pub != nil {
+		s.engine.unpublished()
 		errs.Add(pub.Close())
 	}
 	return errs.Aggregate()
 }
 
-// Logf wraps simple printf log
+// Logf wraps simple printf log as info record of the engine
 func (s *Spec) Logf(format string, v ...interface{}) {
-	if l := s.Logger; l != nil {
-		l.Printf(format, v...)
-	}
+	s.Log().Info(strings.TrimRight(fmt.Sprintf(format, v...), "\n"))
 }
 
-// Logfln wraps simple printf log with newline
+// Logfln is the same as Logf
 func (s *Spec) Logfln(format string, v ...interface{}) {
-	if l := s.Logger; l != nil {
-		l.Printf(format+"\n", v...)
-	}
+	s.Logf(format, v...)
 }
 
 // ID implements mqhub.Identifier
@@ -211,6 +343,9 @@ func (s *ComponentSpec) ID() string {
 func (s *ComponentSpec) Endpoints() []mqhub.Endpoint {
 	if s.Instance != nil {
 		if stateful, ok := s.Instance.(v0.Stateful); ok {
+			if s.Root != nil && s.Root.Metrics != nil {
+				return s.meterEndpoints(stateful.Endpoints())
+			}
 			return stateful.Endpoints()
 		}
 	}
@@ -264,6 +399,13 @@ func (s *ComponentSpec) Children() []v0.ComponentRef {
 	return children
 }
 
+// ReportExit implements v0.ExitReporter
+func (s *ComponentSpec) ReportExit(comp v0.Component, err error) {
+	// the report may come from inside LifecycleCtl.Stop when the
+	// engine is holding the lock, so always handle it asynchronously
+	go s.Root.handleExit(s, comp, err)
+}
+
 // FullID returns the absolute ID reflecting the hierarchy
 func (s *ComponentSpec) FullID() (id string) {
 	for spec := s; spec != nil; spec = spec.ParentSpec {
@@ -276,6 +418,24 @@ func (s *ComponentSpec) FullID() (id string) {
 	return
 }
 
+// specPath returns the dotted path of the component in the spec,
+// e.g. components.head.components.pan
+func (s *ComponentSpec) specPath() string {
+	path := "components." + s.LocalID
+	for spec := s.ParentSpec; spec != nil; spec = spec.ParentSpec {
+		path = "components." + spec.LocalID + "." + path
+	}
+	return path
+}
+
+// configPositions locates the config values in the spec file
+func (s *ComponentSpec) configPositions() Positions {
+	if s.Root == nil || s.Root.positions == nil {
+		return nil
+	}
+	return s.Root.positions.Sub(s.specPath() + ".config")
+}
+
 // Logf wraps s.Root.Logf
 func (s *ComponentSpec) Logf(format string, v ...interface{}) {
 	s.Root.Logf(format, v...)
@@ -316,20 +476,38 @@ func (s *ComponentSpec) resolveStart(all map[string]*ComponentSpec) {
 func (s *ComponentSpec) buildDependencies(errs *errors.AggregatedError) {
 	s.ResolvedInjections = make(map[string]interface{})
 	for name, injectSpec := range s.InjectSpecs {
+		if injectSpec.IsValue() {
+			value, err := s.resolveValue(name, injectSpec)
+			if err != nil {
+				errs.Add(fmt.Errorf("%s: injection %s: %v", s.FullID(), name, err))
+			} else {
+				s.ResolvedInjections[name] = value
+			}
+			continue
+		}
 		if injectSpec.Type != InjectRef {
 			continue
 		}
-		if injectSpec.ID == "" {
+		if injectSpec.ID == "" && !injectSpec.Multiple() {
 			errs.Add(fmt.Errorf("%s: injection 'id' required %s", s.FullID(), name))
+			continue
 		}
-		spec := s.resolveIDRef(injectSpec.ID)
-		if spec == nil {
-			errs.Add(fmt.Errorf("%s: unresolved inject %s", s.FullID(), injectSpec.ID))
+		specs, err := s.resolveRefs(injectSpec)
+		if err != nil {
+			errs.Add(fmt.Errorf("%s: %v", s.FullID(), err))
 			continue
 		}
-		s.depends[spec.FullID()] = spec
-		spec.activates[s.FullID()] = s
-		s.ResolvedInjections[name] = spec
+		refs := make(InjectionList, 0, len(specs))
+		for _, spec := range specs {
+			s.depends[spec.FullID()] = spec
+			spec.activates[s.FullID()] = s
+			refs = append(refs, spec)
+		}
+		if injectSpec.Multiple() {
+			s.ResolvedInjections[name] = refs
+		} else if len(refs) > 0 {
+			s.ResolvedInjections[name] = refs[0]
+		}
 	}
 	for _, id := range s.After {
 		spec := s.resolveIDRef(id)
@@ -390,19 +568,13 @@ func (s *ComponentSpec) resolveIDRef(idRef string) *ComponentSpec {
 	return spec
 }
 
-func (s *ComponentSpec) activate(all map[string]*ComponentSpec) (ready []*ComponentSpec) {
-	if len(s.depends) == 0 && all[s.FullID()] != nil {
-		ready = append(ready, s)
-		delete(all, s.FullID())
-		for _, spec := range s.activates {
-			delete(spec.depends, s.FullID())
-			ready = append(ready, spec.activate(all)...)
+func sortInitGroup(group []*ComponentSpec) {
+	sort.Slice(group, func(i, j int) bool {
+		if group[i].Priority != group[j].Priority {
+			return group[i].Priority > group[j].Priority
 		}
-	}
-	for _, spec := range s.ChildSpecs {
-		ready = append(ready, spec.activate(all)...)
-	}
-	return
+		return group[i].FullID() < group[j].FullID()
+	})
 }
 
 func (s *ComponentSpec) resolveType(resolver v0.ComponentTypeResolver, errs *errors.AggregatedError) {
@@ -423,17 +595,33 @@ func (s *ComponentSpec) resolveType(resolver v0.ComponentTypeResolver, errs *err
 
 func (s *ComponentSpec) resolveConnections(connector mqhub.Connector, errs *errors.AggregatedError) {
 	for name, inject := range s.InjectSpecs {
-		if inject.Type != InjectHub || inject.Path == "" {
+		if inject.Type != InjectHub {
 			continue
 		}
-		compRef, endpoint := path.Split(inject.Path)
-		s.ResolvedInjections[name] = connector.Describe(compRef).Endpoint(endpoint)
+		if inject.Multiple() {
+			paths := inject.Paths
+			if inject.Path != "" {
+				paths = append([]string{inject.Path}, paths...)
+			}
+			refs := make(InjectionList, 0, len(paths))
+			for _, p := range paths {
+				refs = append(refs, describeEndpoint(connector, p))
+			}
+			s.ResolvedInjections[name] = refs
+		} else if inject.Path != "" {
+			s.ResolvedInjections[name] = describeEndpoint(connector, inject.Path)
+		}
 	}
 	for _, spec := range s.ChildSpecs {
 		spec.resolveConnections(connector, errs)
 	}
 }
 
+func describeEndpoint(connector mqhub.Connector, endpointPath string) mqhub.EndpointRef {
+	compRef, endpoint := path.Split(endpointPath)
+	return connector.Describe(compRef).Endpoint(endpoint)
+}
+
 func (s *ComponentSpec) connect(errs *errors.AggregatedError) {
 	if s.ResolvedType == nil {
 		return
@@ -446,28 +634,53 @@ func (s *ComponentSpec) connect(errs *errors.AggregatedError) {
 	instance, err := factory.CreateComponent(s)
 	if !errs.Add(err) {
 		s.Instance = instance
+		if instrumented, ok := instance.(v0.Instrumented); ok {
+			instrumented.RegisterMetrics(s.metrics())
+		}
+		s.setState(StateCreated, nil)
+	} else {
+		s.setState(StateFailed, err)
 	}
 }
 
-func (s *ComponentSpec) start(errs *errors.AggregatedError) {
+func (s *ComponentSpec) start() (err error) {
 	if s.Instance == nil {
 		return
 	}
 	if ctl, ok := s.Instance.(v0.LifecycleCtl); ok {
 		s.Logfln("Start %s", s.FullID())
-		s.started = !errs.Add(ctl.Start())
+		err = ctl.Start()
+		s.started = err == nil
+		s.startedAt = time.Now()
+	}
+	if err != nil {
+		s.setState(StateFailed, err)
+	} else {
+		s.setState(StateStarted, nil)
 	}
+	return
 }
 
-func (s *ComponentSpec) stop() error {
-	inst := s.Instance
-	s.Instance = nil
-	if inst != nil && s.started {
+// halt stops the instance if started, the instance is kept
+func (s *ComponentSpec) halt() error {
+	if s.Instance != nil && s.started {
 		s.started = false
-		if ctl, ok := inst.(v0.LifecycleCtl); ok {
+		s.setState(StateStopped, nil)
+		if ctl, ok := s.Instance.(v0.LifecycleCtl); ok {
 			s.Logfln("Stop %s", s.FullID())
 			return ctl.Stop()
 		}
 	}
 	return nil
 }
+
+func (s *ComponentSpec) stop() error {
+	err := s.halt()
+	if s.Instance != nil {
+		s.Instance = nil
+		if s.state != StateFailed {
+			s.setState(StateStopped, nil)
+		}
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+package engine
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/easeway/langx.go/errors"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// strictChecker reports the keys not accepted by the target type
+// and the values not matching the types of fields
+type strictChecker struct {
+	name      string
+	positions Positions
+	errs      errors.AggregatedError
+}
+
+func (c *strictChecker) check(t reflect.Type, value interface{}, path string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value == nil || t.Kind() == reflect.Interface {
+		return
+	}
+	if reflect.PtrTo(t).Implements(configValueType) {
+		if err := reflect.New(t).Interface().(ConfigValue).ParseConfig(value); err != nil {
+			c.add(path, fmt.Sprintf("config %s: %v", path, err))
+		}
+		return
+	}
+	if t.Implements(textUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			c.typeError(path, "map", value)
+			return
+		}
+		fields := ConfigFields(t)
+		for _, key := range sortedMapKeys(m) {
+			f := findConfigField(fields, key)
+			if f == nil {
+				c.unknownKey(joinPath(path, key), key, fields)
+				continue
+			}
+			c.check(f.Field.Type, m[key], joinPath(path, key))
+		}
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			c.typeError(path, "map", value)
+			return
+		}
+		for _, key := range sortedMapKeys(m) {
+			c.check(t.Elem(), m[key], joinPath(path, key))
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			if _, isStr := value.(string); isStr && t.Elem().Kind() == reflect.Uint8 {
+				return
+			}
+			c.typeError(path, "list", value)
+			return
+		}
+		for n, item := range list {
+			c.check(t.Elem(), item, joinPath(path, fmt.Sprint(n)))
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			c.typeError(path, "bool", value)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			c.typeError(path, "string", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := numberOf(value); !ok || n != math.Trunc(n) {
+			c.typeError(path, "integer", value)
+		} else if reflect.Zero(t).OverflowInt(int64(n)) {
+			c.typeError(path, t.String(), value)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := numberOf(value); !ok || n != math.Trunc(n) || n < 0 {
+			c.typeError(path, "non-negative integer", value)
+		} else if reflect.Zero(t).OverflowUint(uint64(n)) {
+			c.typeError(path, t.String(), value)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := numberOf(value); !ok {
+			c.typeError(path, "number", value)
+		}
+	}
+}
+
+func (c *strictChecker) unknownKey(path, key string, fields []ConfigField) {
+	msg := "unknown config key " + path
+	if suggestion := suggestKey(key, fields); suggestion != "" {
+		msg += ", did you mean " + suggestion + "?"
+	}
+	c.add(path, msg)
+}
+
+func (c *strictChecker) typeError(path, expect string, value interface{}) {
+	c.add(path, fmt.Sprintf("config %s: expect %s, got %T %v", path, expect, value, value))
+}
+
+func (c *strictChecker) add(path, msg string) {
+	if c.name != "" {
+		msg = c.name + ": " + msg
+	}
+	if pos, ok := c.positions.Lookup(path); ok {
+		msg += " (" + pos.String() + ")"
+	}
+	c.errs.Add(fmt.Errorf("%s", msg))
+}
+
+func numberOf(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// suggestKey finds the field key closest to the unknown key
+func suggestKey(key string, fields []ConfigField) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(s))
+	}
+	best, bestDist := "", len(key)/3+2
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+	}
+	sort.Strings(keys)
+	for _, candidate := range keys {
+		if normalize(candidate) == normalize(key) {
+			return candidate
+		}
+		if dist := editDistance(strings.ToLower(key), strings.ToLower(candidate)); dist < bestDist {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min3(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testServoType struct{}
+
+func (t *testServoType) Name() string                 { return "test.servo" }
+func (t *testServoType) Description() string          { return t.Name() }
+func (t *testServoType) Factory() v0.ComponentFactory { return t }
+func (t *testServoType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	inst := &testServo{ref: ref}
+	return inst, SetupComponent(inst, ref)
+}
+
+type testServo struct {
+	Pin      string `map:"pin"`
+	PulseMin int    `map:"pulse-min"`
+	PulseMax int    `map:"pulse-max"`
+	Limits   struct {
+		Min float64 `map:"min"`
+	} `map:"limits"`
+	ref v0.ComponentRef
+}
+
+func (s *testServo) Ref() v0.ComponentRef   { return s.ref }
+func (s *testServo) Type() v0.ComponentType { return &testServoType{} }
+
+func TestStrictConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "talk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	fn := filepath.Join(dir, "robot.yaml")
+	assert.NoError(t, ioutil.WriteFile(fn, []byte(`---
+name: robot
+components:
+  head:
+    components:
+      pan:
+        type: test.servo
+        config:
+          pin: "12"
+          pulse_min: 600
+          pulse-max: 2400.5
+          limits:
+            min: low
+`), 0644))
+	spec, err := LoadSpec(fn, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tester := makeTester(t)
+	tester.addTypes(&testServoType{})
+	spec.TypeResolver = tester.types
+	assert.NoError(t, spec.Resolve())
+	err = spec.Connect(tester)
+	if assert.Error(t, err) {
+		msg := err.Error()
+		assert.Contains(t, msg, "head/pan: unknown config key pulse_min, did you mean pulse-min? ("+fn+":10:11)")
+		assert.Contains(t, msg, "head/pan: config pulse-max: expect integer, got float64 2400.5 ("+fn+":11:11)")
+		assert.Contains(t, msg, "head/pan: config limits.min: expect number, got string low ("+fn+":13:13)")
+	}
+}
+
+func TestStrictMapConfig(t *testing.T) {
+	var out struct {
+		Rate  uint8    `map:"rate"`
+		Names []string `map:"names"`
+		Any   interface{}
+	}
+	conf := &MapConfig{Map: map[string]interface{}{
+		"rate":  300,
+		"names": []interface{}{"a", 1},
+		"any":   true,
+		"rat":   1,
+	}, Strict: true}
+	err := conf.As(&out)
+	if assert.Error(t, err) {
+		msg := err.Error()
+		assert.Contains(t, msg, "config rate: expect uint8, got int 300")
+		assert.Contains(t, msg, "config names.1: expect string, got int 1")
+		assert.Contains(t, msg, "unknown config key rat, did you mean rate?")
+		assert.NotContains(t, msg, "any")
+	}
+	conf.Strict = false
+	delete(conf.Map, "names")
+	assert.NoError(t, conf.As(&out))
+}
+
+func TestYAMLPositions(t *testing.T) {
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(bytes.NewBufferString(`---
+name: robot
+base: &base
+  pin: 7
+  rate: 50
+components:
+  cmd:
+    config:
+      script: |
+        a: 1
+      args:
+        - a
+        - key: b
+          value: c
+      env: [x, {"y": z}]
+  "servo":
+    config: {<<: *base, rate: 60}
+  long:
+    description: >
+      key: not a key
+    ref: *base
+`)))
+	assert.Equal(t, Positions{
+		"name":                               {Line: 2, Column: 1},
+		"base":                               {Line: 3, Column: 1},
+		"base.pin":                           {Line: 4, Column: 3},
+		"base.rate":                          {Line: 5, Column: 3},
+		"components":                         {Line: 6, Column: 1},
+		"components.cmd":                     {Line: 7, Column: 3},
+		"components.cmd.config":              {Line: 8, Column: 5},
+		"components.cmd.config.script":       {Line: 9, Column: 7},
+		"components.cmd.config.args":         {Line: 11, Column: 7},
+		"components.cmd.config.args.0":       {Line: 12, Column: 11},
+		"components.cmd.config.args.1":       {Line: 13, Column: 11},
+		"components.cmd.config.args.1.key":   {Line: 13, Column: 11},
+		"components.cmd.config.args.1.value": {Line: 14, Column: 11},
+		"components.cmd.config.env":          {Line: 15, Column: 7},
+		"components.cmd.config.env.0":        {Line: 15, Column: 13},
+		"components.cmd.config.env.1":        {Line: 15, Column: 16},
+		"components.cmd.config.env.1.y":      {Line: 15, Column: 17},
+		"components.servo":                   {Line: 16, Column: 3},
+		"components.servo.config":            {Line: 17, Column: 5},
+		"components.servo.config.pin":        {Line: 4, Column: 3},
+		"components.servo.config.rate":       {Line: 17, Column: 25},
+		"components.long":                    {Line: 18, Column: 3},
+		"components.long.description":        {Line: 19, Column: 5},
+		"components.long.ref":                {Line: 21, Column: 5},
+		"components.long.ref.pin":            {Line: 4, Column: 3},
+		"components.long.ref.rate":           {Line: 5, Column: 3},
+	}, conf.Positions)
+	pos, ok := conf.Positions.Sub("components.cmd").Lookup("config.env.0.x")
+	assert.True(t, ok)
+	assert.Equal(t, "line 15 column 13", pos.String())
+	pos.File = "robot.yaml"
+	assert.Equal(t, "robot.yaml:15:13", pos.String())
+}
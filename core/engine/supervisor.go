@@ -0,0 +1,211 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// Restart policies
+const (
+	// RestartNever never restarts the component, it's the default
+	RestartNever = "never"
+	// RestartOnFailure restarts the component when it exits with error
+	RestartOnFailure = "on-failure"
+	// RestartAlways restarts the component whenever it exits
+	RestartAlways = "always"
+)
+
+var (
+	// DefaultRestartBackoff is the delay before the first restart
+	DefaultRestartBackoff = time.Second
+	// MaxRestartBackoff caps the delay which doubles on each retry
+	MaxRestartBackoff = time.Minute
+	// HealthyUptime is how long a component must stay up after start
+	// to be considered healthy, its retries and backoff are reset then
+	HealthyUptime = time.Minute
+)
+
+func (s *ComponentSpec) checkRestartPolicy(errs *errors.AggregatedError) {
+	switch s.Restart {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		errs.Add(fmt.Errorf("%s: invalid restart policy %s", s.FullID(), s.Restart))
+	}
+	if s.Backoff < 0 || s.MaxRetries < 0 {
+		errs.Add(fmt.Errorf("%s: backoff and max-retries must not be negative", s.FullID()))
+	}
+	for _, spec := range s.ChildSpecs {
+		spec.checkRestartPolicy(errs)
+	}
+}
+
+// restartDelay decides whether the component should be restarted
+// after exit with err, and how long to wait before that
+func (s *ComponentSpec) restartDelay(err error) (time.Duration, bool) {
+	switch s.Restart {
+	case RestartAlways:
+	case RestartOnFailure:
+		if err == nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if s.MaxRetries > 0 && s.retries >= s.MaxRetries {
+		s.Logfln("Give up restarting %s after %d retries", s.FullID(), s.retries)
+		return 0, false
+	}
+	delay := DefaultRestartBackoff
+	if s.Backoff > 0 {
+		delay = s.Backoff.Duration()
+	}
+	for i := 0; i < s.retries && delay < MaxRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > MaxRestartBackoff {
+		delay = MaxRestartBackoff
+	}
+	s.retries++
+	return delay, true
+}
+
+func (s *Spec) handleExit(comp *ComponentSpec, inst v0.Component, err error) {
+	s.lock.Lock()
+	if !s.running || comp.Instance != inst || !comp.started {
+		// stale report or the component is being stopped
+		s.lock.Unlock()
+		return
+	}
+	comp.started = false
+	if time.Since(comp.startedAt) >= HealthyUptime {
+		comp.retries = 0
+	}
+	if err != nil {
+		s.Logfln("%s failed: %v", comp.FullID(), err)
+		comp.setState(StateFailed, err)
+	} else {
+		s.Logfln("%s exited", comp.FullID())
+		comp.setState(StateStopped, nil)
+	}
+	s.lock.Unlock()
+	s.supervise(comp, inst, err)
+}
+
+func (s *Spec) supervise(comp *ComponentSpec, inst v0.Component, err error) {
+	for {
+		s.lock.Lock()
+		if !s.running || comp.Instance != inst {
+			s.lock.Unlock()
+			return
+		}
+		delay, restart := comp.restartDelay(err)
+		s.lock.Unlock()
+		if !restart {
+			return
+		}
+
+		time.Sleep(delay)
+
+		s.lock.Lock()
+		if !s.running || comp.Instance != inst {
+			s.lock.Unlock()
+			return
+		}
+		err = s.restart(comp)
+		inst = comp.Instance
+		s.lock.Unlock()
+		if err == nil {
+			return
+		}
+		s.Logfln("Restart %s failed: %v", comp.FullID(), err)
+	}
+}
+
+// restart re-creates the component and all components depending on it,
+// so the dependents are re-wired with the new instance
+func (s *Spec) restart(comp *ComponentSpec) error {
+	exited := !comp.started
+	comp.metrics().Counter("talk_component_restarts_total", "Restarts of components").Inc()
+	affected := s.dependentsOf(comp)
+	var haltErrs errors.AggregatedError
+	for i := len(affected); i > 0; i-- {
+		haltErrs.Add(affected[i-1].halt())
+	}
+	if ctl, ok := comp.Instance.(v0.LifecycleCtl); ok && exited {
+		// the instance exited by itself, release its resources
+		if err := ctl.Stop(); err != nil {
+			s.Logfln("Stop %s: %v", comp.FullID(), err)
+		}
+	}
+	if err := haltErrs.Aggregate(); err != nil {
+		s.Logfln("Stop dependents of %s: %v", comp.FullID(), err)
+	}
+
+	s.Logfln("Restart %s", comp.FullID())
+	for _, spec := range affected {
+		spec.Instance = nil
+	}
+	for _, spec := range affected {
+		var errs errors.AggregatedError
+		spec.connect(&errs)
+		if err := errs.Aggregate(); err != nil {
+			return err
+		}
+		if err := spec.start(); err != nil {
+			return err
+		}
+	}
+	return s.republish()
+}
+
+// dependentsOf returns comp and all components transitively
+// depending on it, in init order
+func (s *Spec) dependentsOf(comp *ComponentSpec) []*ComponentSpec {
+	set := map[*ComponentSpec]bool{comp: true}
+	pending := []*ComponentSpec{comp}
+	for len(pending) > 0 {
+		spec := pending[0]
+		pending = pending[1:]
+		for _, dep := range spec.activates {
+			if !set[dep] {
+				set[dep] = true
+				pending = append(pending, dep)
+			}
+		}
+	}
+	var ordered []*ComponentSpec
+	for _, group := range s.initOrder {
+		for _, spec := range group {
+			if set[spec] {
+				ordered = append(ordered, spec)
+			}
+		}
+	}
+	return ordered
+}
+
+// republish publishes the spec again to pick up endpoints of new instances
+func (s *Spec) republish() error {
+	if s.connector == nil {
+		return nil
+	}
+	if pub := s.publication; pub != nil {
+		s.publication = nil
+		pub.Close()
+	}
+	if s.engine == nil {
+		s.engine = newEngineComponent(s)
+	}
+	pub, err := s.connector.Publish(s)
+	if err == nil {
+		s.publication = pub
+		if s.Metrics != nil {
+			s.meterDataPoints()
+		}
+		s.engine.published()
+	}
+	return err
+}
@@ -0,0 +1,276 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCrashType struct {
+	lock      sync.Mutex
+	instances map[string][]*testCrash
+}
+
+func (t *testCrashType) Name() string                 { return "test.crash" }
+func (t *testCrashType) Description() string          { return t.Name() }
+func (t *testCrashType) Factory() v0.ComponentFactory { return t }
+func (t *testCrashType) CreateComponent(ref v0.ComponentRef) (v0.Component, error) {
+	inst := &testCrash{typ: t, ref: ref}
+	if err := SetupComponent(inst, ref); err != nil {
+		return nil, err
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.instances == nil {
+		t.instances = make(map[string][]*testCrash)
+	}
+	id := ref.MessagePath()
+	t.instances[id] = append(t.instances[id], inst)
+	return inst, nil
+}
+
+func (t *testCrashType) created(id string) []*testCrash {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return append([]*testCrash{}, t.instances[id]...)
+}
+
+func (t *testCrashType) waitCreated(id string, count int) []*testCrash {
+	for i := 0; i < 100; i++ {
+		if insts := t.created(id); len(insts) >= count {
+			return insts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return t.created(id)
+}
+
+type testCrash struct {
+	Dep      v0.LifecycleCtl `inject:"dep" map:"-"`
+	PulseMin int             `map:"pulse-min"`
+	FailStop bool            `map:"fail-stop"`
+
+	typ     *testCrashType
+	ref     v0.ComponentRef
+	lock    sync.Mutex
+	running bool
+}
+
+func (c *testCrash) Ref() v0.ComponentRef   { return c.ref }
+func (c *testCrash) Type() v0.ComponentType { return c.typ }
+func (c *testCrash) Start() error           { c.setRunning(true); return nil }
+
+func (c *testCrash) Stop() error {
+	c.setRunning(false)
+	if c.FailStop {
+		return fmt.Errorf("stop failed")
+	}
+	return nil
+}
+
+func (c *testCrash) setRunning(running bool) {
+	c.lock.Lock()
+	c.running = running
+	c.lock.Unlock()
+}
+
+func (c *testCrash) isRunning() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.running
+}
+
+func (c *testCrash) exit(err error) {
+	c.setRunning(false)
+	ReportExit(c, err)
+}
+
+func TestRestartPolicies(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA, typeInstanceB)
+	spec := tester.spec(`---
+        name: test
+        components:
+          base:
+            type: test.A
+          a:
+            type: test.crash
+            restart: on-failure
+            backoff: 1
+            max-retries: 1
+            inject:
+              dep:
+                type: ref
+                id: base
+          b:
+            type: test.B
+            inject:
+              a:
+                type: ref
+                id: a
+              ref:
+                type: hub
+                path: remote/component/endpoint
+          c:
+            type: test.crash
+            restart: on-failure
+            inject:
+              dep:
+                type: ref
+                id: base
+     `)
+	assert.NoError(t, spec.Start())
+	b0 := newSpecTester(t, spec).component("b").Instance.(*testInstanceB)
+	a := compType.created("a")
+	if !assert.Len(t, a, 1) {
+		return
+	}
+	assert.True(t, a[0].isRunning())
+
+	// normal exit is not restarted by on-failure
+	compType.created("c")[0].exit(nil)
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, compType.created("c"), 1)
+
+	a[0].exit(fmt.Errorf("crashed"))
+	a = compType.waitCreated("a", 2)
+	if assert.Len(t, a, 2) {
+		assert.True(t, a[1].isRunning())
+		spec.lock.Lock()
+		b1 := newSpecTester(t, spec).component("b").Instance.(*testInstanceB)
+		spec.lock.Unlock()
+		assert.False(t, b0 == b1)
+		assert.True(t, b1.Ctl == v0.LifecycleCtl(a[1]))
+	}
+
+	// max-retries reached
+	a[1].exit(fmt.Errorf("crashed again"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, compType.created("a"), 2)
+
+	assert.NoError(t, spec.Disconnect())
+}
+
+func TestRestartBackoff(t *testing.T) {
+	conf := NewMapConfig()
+	assert.NoError(t, conf.Load(strings.NewReader(`---
+        name: test
+        components:
+          a:
+            restart: always
+            backoff: 2s
+          b:
+            restart: always
+            backoff: 500
+     `)))
+	spec, err := ParseSpec(conf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	delay, ok := spec.ChildSpecs["a"].restartDelay(nil)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, delay)
+	delay, _ = spec.ChildSpecs["a"].restartDelay(nil)
+	assert.Equal(t, 4*time.Second, delay)
+	delay, _ = spec.ChildSpecs["b"].restartDelay(nil)
+	assert.Equal(t, 500*time.Millisecond, delay)
+}
+
+func TestRestartRetriesReset(t *testing.T) {
+	uptime := HealthyUptime
+	HealthyUptime = 50 * time.Millisecond
+	defer func() { HealthyUptime = uptime }()
+
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.spec(`---
+        name: test
+        components:
+          base:
+            type: test.A
+          a:
+            type: test.crash
+            restart: on-failure
+            backoff: 1ms
+            max-retries: 1
+            inject:
+              dep:
+                type: ref
+                id: base
+     `)
+	assert.NoError(t, spec.Start())
+	compType.created("a")[0].exit(fmt.Errorf("crashed"))
+	a := compType.waitCreated("a", 2)
+	if !assert.Len(t, a, 2) {
+		return
+	}
+
+	// stayed up long enough, retries are reset
+	time.Sleep(60 * time.Millisecond)
+	a[1].exit(fmt.Errorf("crashed again"))
+	a = compType.waitCreated("a", 3)
+	if !assert.Len(t, a, 3) {
+		return
+	}
+
+	// crashed shortly after restart, max-retries reached
+	a[2].exit(fmt.Errorf("crashed quickly"))
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, compType.created("a"), 3)
+
+	assert.NoError(t, spec.Disconnect())
+}
+
+func TestRestartDependentStopFailure(t *testing.T) {
+	tester := makeTester(t)
+	compType := &testCrashType{}
+	tester.addTypes(compType, typeInstanceA)
+	spec := tester.spec(`---
+        name: test
+        components:
+          base:
+            type: test.A
+          a:
+            type: test.crash
+            restart: on-failure
+            backoff: 1
+            max-retries: 1
+            inject:
+              dep:
+                type: ref
+                id: base
+          b:
+            type: test.crash
+            config:
+              fail-stop: true
+            inject:
+              dep:
+                type: ref
+                id: a
+          c:
+            type: test.crash
+            inject:
+              dep:
+                type: ref
+                id: b
+     `)
+	assert.NoError(t, spec.Start())
+	compType.created("a")[0].exit(fmt.Errorf("crashed"))
+	for _, id := range []string{"a", "b", "c"} {
+		if insts := compType.waitCreated(id, 2); assert.Len(t, insts, 2, id) {
+			assert.True(t, insts[1].isRunning(), id)
+		}
+	}
+	spec.lock.Lock()
+	c := newSpecTester(t, spec).component("c").Instance.(*testCrash)
+	spec.lock.Unlock()
+	assert.True(t, c.Dep == v0.LifecycleCtl(compType.created("b")[1]))
+	spec.Disconnect()
+}
@@ -0,0 +1,255 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigValue is implemented by config field types parsing the raw
+// config value (a number, string, list or map) by themselves
+type ConfigValue interface {
+	ParseConfig(value interface{}) error
+}
+
+var configValueType = reflect.TypeOf((*ConfigValue)(nil)).Elem()
+
+// Duration is a config value of time, e.g. 500ms, 2s, 1m30s,
+// a bare number is in milliseconds
+type Duration time.Duration
+
+// ParseConfig implements ConfigValue
+func (d *Duration) ParseConfig(value interface{}) error {
+	if str, ok := value.(string); ok {
+		if parsed, err := time.ParseDuration(strings.TrimSpace(str)); err == nil {
+			*d = Duration(parsed)
+			return nil
+		}
+	}
+	ns, err := parseQuantity(value, durationUnits, "ms")
+	*d = Duration(math.Round(ns))
+	return err
+}
+
+// Duration converts to time.Duration
+func (d Duration) Duration() time.Duration {
+	return time.Duration(d)
+}
+
+// String implements fmt.Stringer
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// Frequency is a config value in Hz, e.g. 50Hz, 1.5kHz,
+// a bare number is in Hz
+type Frequency float64
+
+// ParseConfig implements ConfigValue
+func (f *Frequency) ParseConfig(value interface{}) error {
+	hz, err := parseQuantity(value, frequencyUnits, "Hz")
+	*f = Frequency(hz)
+	return err
+}
+
+// Hz returns the frequency in Hz
+func (f Frequency) Hz() float64 {
+	return float64(f)
+}
+
+// Period returns the duration of one cycle
+func (f Frequency) Period() time.Duration {
+	if f <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(f))
+}
+
+// String implements fmt.Stringer
+func (f Frequency) String() string {
+	return strconv.FormatFloat(float64(f), 'g', -1, 64) + "Hz"
+}
+
+// Angle is a config value in radians, e.g. 90deg, 1.2rad,
+// a bare number is in degrees
+type Angle float64
+
+// ParseConfig implements ConfigValue
+func (a *Angle) ParseConfig(value interface{}) error {
+	rad, err := parseQuantity(value, angleUnits, "deg")
+	*a = Angle(rad)
+	return err
+}
+
+// Rad returns the angle in radians
+func (a Angle) Rad() float64 {
+	return float64(a)
+}
+
+// Deg returns the angle in degrees
+func (a Angle) Deg() float64 {
+	return float64(a) * 180 / math.Pi
+}
+
+// String implements fmt.Stringer
+func (a Angle) String() string {
+	return strconv.FormatFloat(a.Deg(), 'g', -1, 64) + "deg"
+}
+
+// Voltage is a config value in volts, e.g. 3.3V, 500mV,
+// a bare number is in volts
+type Voltage float64
+
+// ParseConfig implements ConfigValue
+func (v *Voltage) ParseConfig(value interface{}) error {
+	volts, err := parseQuantity(value, voltageUnits, "V")
+	*v = Voltage(volts)
+	return err
+}
+
+// Volts returns the voltage in volts
+func (v Voltage) Volts() float64 {
+	return float64(v)
+}
+
+// String implements fmt.Stringer
+func (v Voltage) String() string {
+	return strconv.FormatFloat(float64(v), 'g', -1, 64) + "V"
+}
+
+var (
+	durationUnits = map[string]float64{
+		"ns":  1,
+		"us":  1e3,
+		"µs":  1e3,
+		"ms":  1e6,
+		"s":   1e9,
+		"min": 60e9,
+		"h":   3600e9,
+	}
+	frequencyUnits = map[string]float64{
+		"Hz":  1,
+		"kHz": 1e3,
+		"MHz": 1e6,
+	}
+	angleUnits = map[string]float64{
+		"rad": 1,
+		"deg": math.Pi / 180,
+		"°":   math.Pi / 180,
+	}
+	voltageUnits = map[string]float64{
+		"V":  1,
+		"mV": 1e-3,
+		"kV": 1e3,
+	}
+)
+
+// parseQuantity converts a number or a string of number with unit
+// to the base unit, a number without unit is in defaultUnit
+func parseQuantity(value interface{}, units map[string]float64, defaultUnit string) (float64, error) {
+	if n, ok := numberOf(value); ok {
+		return n * units[defaultUnit], nil
+	}
+	str, ok := value.(string)
+	if !ok {
+		return 0, fmt.Errorf("expect number with unit, got %T %v", value, value)
+	}
+	str = strings.TrimSpace(str)
+	pos := strings.IndexFunc(str, func(r rune) bool {
+		return !strings.ContainsRune("+-.0123456789eE", r)
+	})
+	num, unit := str, defaultUnit
+	if pos >= 0 {
+		num, unit = str[:pos], strings.TrimSpace(str[pos:])
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number in %q", str)
+	}
+	scale, ok := units[unit]
+	if !ok {
+		for name, s := range units {
+			if strings.EqualFold(name, unit) {
+				scale, ok = s, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q in %q", unit, str)
+	}
+	return n * scale, nil
+}
+
+// parseConfigValues replaces the raw values of ConfigValue fields
+// with the parsed ones, the containers are copied on write
+func parseConfigValues(t reflect.Type, value interface{}) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if value == nil {
+		return nil, nil
+	}
+	if reflect.PtrTo(t).Implements(configValueType) {
+		parsed := reflect.New(t)
+		if err := parsed.Interface().(ConfigValue).ParseConfig(value); err != nil {
+			return nil, err
+		}
+		return parsed.Elem().Interface(), nil
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		var fields []ConfigField
+		if t.Kind() == reflect.Struct {
+			fields = ConfigFields(t)
+		}
+		copied, owned := m, false
+		for key, item := range m {
+			elemType := t
+			if t.Kind() == reflect.Map {
+				elemType = t.Elem()
+			} else if f := findConfigField(fields, key); f != nil {
+				elemType = f.Field.Type
+			} else {
+				continue
+			}
+			parsed, err := parseConfigValues(elemType, item)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			if reflect.DeepEqual(parsed, item) {
+				continue
+			}
+			if !owned {
+				copied, owned = make(map[string]interface{}), true
+				for k, v := range m {
+					copied[k] = v
+				}
+			}
+			copied[key] = parsed
+		}
+		return copied, nil
+	case reflect.Slice, reflect.Array:
+		list, ok := value.([]interface{})
+		if !ok {
+			return value, nil
+		}
+		copied := make([]interface{}, len(list))
+		for n, item := range list {
+			parsed, err := parseConfigValues(t.Elem(), item)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %v", n, err)
+			}
+			copied[n] = parsed
+		}
+		return copied, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitConfigValues(t *testing.T) {
+	var out struct {
+		Wait     Duration           `map:"wait"`
+		Interval Duration           `map:"interval"`
+		Timeout  *Duration          `map:"timeout"`
+		Freq     Frequency          `map:"freq"`
+		Rate     Frequency          `map:"rate"`
+		Pan      Angle              `map:"pan"`
+		Tilt     *Angle             `map:"tilt"`
+		Supply   Voltage            `map:"supply"`
+		Ref      Voltage            `map:"ref"`
+		Steps    []Angle            `map:"steps"`
+		Limits   map[string]Voltage `map:"limits"`
+	}
+	config := map[string]interface{}{
+		"wait":     "500ms",
+		"interval": 20,
+		"timeout":  "1m30s",
+		"freq":     "50Hz",
+		"rate":     "1.5kHz",
+		"pan":      "90deg",
+		"tilt":     "1.2rad",
+		"supply":   "3.3V",
+		"ref":      "500 mV",
+		"steps":    []interface{}{45, "-45deg"},
+		"limits":   map[string]interface{}{"max": 5},
+	}
+	conf := &MapConfig{Map: config, Strict: true}
+	assert.NoError(t, conf.As(&out))
+	assert.Equal(t, 500*time.Millisecond, out.Wait.Duration())
+	assert.Equal(t, 20*time.Millisecond, out.Interval.Duration())
+	assert.Equal(t, 90*time.Second, out.Timeout.Duration())
+	assert.Equal(t, 50.0, out.Freq.Hz())
+	assert.Equal(t, 20*time.Millisecond, out.Freq.Period())
+	assert.Equal(t, 1500.0, out.Rate.Hz())
+	assert.InDelta(t, math.Pi/2, out.Pan.Rad(), 1e-9)
+	assert.InDelta(t, 90, out.Pan.Deg(), 1e-9)
+	assert.InDelta(t, 1.2, out.Tilt.Rad(), 1e-9)
+	assert.InDelta(t, 3.3, out.Supply.Volts(), 1e-9)
+	assert.InDelta(t, 0.5, out.Ref.Volts(), 1e-9)
+	if assert.Len(t, out.Steps, 2) {
+		assert.InDelta(t, 45, out.Steps[0].Deg(), 1e-9)
+		assert.InDelta(t, -45, out.Steps[1].Deg(), 1e-9)
+	}
+	assert.Equal(t, Voltage(5), out.Limits["max"])
+	// the raw config is not changed
+	assert.Equal(t, "500ms", config["wait"])
+	assert.Equal(t, "-45deg", config["steps"].([]interface{})[1])
+}
+
+func TestUnitConfigErrors(t *testing.T) {
+	var out struct {
+		Wait Duration  `map:"wait"`
+		Freq Frequency `map:"freq"`
+	}
+	conf := &MapConfig{Map: map[string]interface{}{
+		"wait": "500 parsecs",
+		"freq": true,
+	}, Strict: true, Name: "pwm"}
+	err := conf.As(&out)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), `pwm: config wait: unknown unit "parsecs" in "500 parsecs"`)
+		assert.Contains(t, err.Error(), "pwm: config freq: expect number with unit, got bool true")
+	}
+	conf.Strict = false
+	assert.Error(t, conf.As(&out))
+}
@@ -0,0 +1,161 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+var endpointRefType = reflect.TypeOf((*mqhub.EndpointRef)(nil)).Elem()
+
+// Validate checks the resolved spec against the prototypes of component
+// types without creating any component.
+// Components of types without prototypes are not checked.
+func (s *Spec) Validate() error {
+	var errs errors.AggregatedError
+	for _, id := range sortedKeys(s.ChildSpecs) {
+		s.ChildSpecs[id].validate(&errs)
+	}
+	return errs.Aggregate()
+}
+
+func (s *ComponentSpec) validate(errs *errors.AggregatedError) {
+	injectNames := sortedInjections(s.InjectSpecs)
+	for _, name := range injectNames {
+		inject := s.InjectSpecs[name]
+		switch inject.Type {
+		case InjectRef, InjectEnv, InjectFile, InjectParam, InjectSecret:
+		case InjectHub:
+			if inject.Path == "" && len(inject.Paths) == 0 {
+				errs.Add(fmt.Errorf("%s: injection 'path' required %s", s.FullID(), name))
+			}
+		default:
+			errs.Add(fmt.Errorf("%s: unknown injection type %q of %s", s.FullID(), inject.Type, name))
+		}
+	}
+
+	if s.ResolvedType == nil {
+		if len(s.Config) > 0 {
+			errs.Add(fmt.Errorf("%s: config without type", s.FullID()))
+		}
+		if len(s.InjectSpecs) > 0 {
+			errs.Add(fmt.Errorf("%s: inject without type", s.FullID()))
+		}
+	} else if proto := prototypeOf(s.ResolvedType); proto != nil {
+		s.validateInjections(proto, injectNames, errs)
+		s.validateConfig(proto, errs)
+	}
+
+	for _, id := range sortedKeys(s.ChildSpecs) {
+		s.ChildSpecs[id].validate(errs)
+	}
+}
+
+func (s *ComponentSpec) validateInjections(proto reflect.Type, names []string, errs *errors.AggregatedError) {
+	fields := InjectFields(proto)
+	injected := make(map[string]bool)
+	for _, name := range names {
+		inject := s.InjectSpecs[name]
+		f, _ := findInjectField(fields, name)
+		if f == nil && inject.IsValue() && findConfigField(ConfigFields(proto), name) != nil {
+			// delivered as config
+			continue
+		}
+		if f == nil {
+			errs.Add(fmt.Errorf("%s: injection %s not accepted by type %s",
+				s.FullID(), name, s.TypeName))
+			continue
+		}
+		injected[f.Name] = true
+		elemType := f.ElemType()
+		if inject.Multiple() && !f.Multiple() {
+			errs.Add(fmt.Errorf("%s: injection %s selects multiple values for %s",
+				s.FullID(), name, f.Field.Type))
+			continue
+		}
+		switch inject.Type {
+		case InjectRef:
+			targets, ok := s.ResolvedInjections[name].(InjectionList)
+			if !ok {
+				targets = InjectionList{s.ResolvedInjections[name]}
+			}
+			for _, item := range targets {
+				target, ok := item.(*ComponentSpec)
+				if !ok {
+					continue
+				}
+				if target.ResolvedType == nil {
+					errs.Add(fmt.Errorf("%s: injection %s refers to %s without type",
+						s.FullID(), name, target.FullID()))
+					continue
+				}
+				targetProto := prototypeOf(target.ResolvedType)
+				if targetProto != nil && !targetProto.AssignableTo(elemType) && !targetProto.ConvertibleTo(elemType) {
+					errs.Add(fmt.Errorf("%s: injection %s type mismatch: %s (%s) is not %s",
+						s.FullID(), name, target.FullID(), target.TypeName, elemType))
+				}
+			}
+		case InjectHub:
+			if !endpointRefType.AssignableTo(elemType) {
+				errs.Add(fmt.Errorf("%s: injection %s type mismatch: hub endpoint is not %s",
+					s.FullID(), name, elemType))
+			}
+		case InjectEnv, InjectFile, InjectParam, InjectSecret:
+			value, ok := s.ResolvedInjections[name]
+			if !ok {
+				continue
+			}
+			if err := f.inject(reflect.New(f.Field.Type).Elem(), "", value); err != nil {
+				errs.Add(fmt.Errorf("%s: injection %s %v: %s is not %s",
+					s.FullID(), name, err, inject.Type, f.Field.Type))
+			}
+		}
+	}
+	unresolved := make([]string, 0, len(fields))
+	for name, f := range fields {
+		if !injected[name] && !f.Optional {
+			unresolved = append(unresolved, name)
+		}
+	}
+	sort.Strings(unresolved)
+	for _, name := range unresolved {
+		errs.Add(fmt.Errorf("%s: injection %s unresolved", s.FullID(), name))
+	}
+}
+
+func (s *ComponentSpec) validateConfig(proto reflect.Type, errs *errors.AggregatedError) {
+	for proto.Kind() == reflect.Ptr {
+		proto = proto.Elem()
+	}
+	conf := componentConfig(s, configWithValues(s.Config, proto, s.ResolvedInjections))
+	errs.Add(conf.As(reflect.New(proto).Interface()))
+}
+
+func prototypeOf(typ v0.ComponentType) reflect.Type {
+	if prototyped, ok := typ.(PrototypedComponentType); ok {
+		return prototyped.ComponentPrototype()
+	}
+	return nil
+}
+
+func sortedKeys(specs map[string]*ComponentSpec) []string {
+	keys := make([]string, 0, len(specs))
+	for key := range specs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedInjections(injects map[string]*InjectionSpec) []string {
+	names := make([]string, 0, len(injects))
+	for name := range injects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
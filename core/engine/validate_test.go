@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	"github.com/stretchr/testify/assert"
+)
+
+type testValidateCtl struct {
+	Param int `map:"param"`
+	ref   v0.ComponentRef
+}
+
+func (c *testValidateCtl) Ref() v0.ComponentRef   { return c.ref }
+func (c *testValidateCtl) Type() v0.ComponentType { return typeValidateCtl }
+func (c *testValidateCtl) Start() error           { return nil }
+func (c *testValidateCtl) Stop() error            { return nil }
+
+type testValidateUser struct {
+	Ctl    v0.LifecycleCtl   `inject:"ctl" map:"-"`
+	Remote mqhub.EndpointRef `inject:"remote" map:"-"`
+	ref    v0.ComponentRef
+}
+
+func (c *testValidateUser) Ref() v0.ComponentRef   { return c.ref }
+func (c *testValidateUser) Type() v0.ComponentType { return typeValidateUser }
+
+var (
+	typeValidateCtl = DefineComponentType("test.validate.ctl", nil).
+			Prototype(&testValidateCtl{})
+	typeValidateUser = DefineComponentType("test.validate.user", nil).
+				Prototype(&testValidateUser{})
+)
+
+func (t *tester) validate(content string) error {
+	conf := NewMapConfig()
+	t.assert.NoError(conf.Load(bytes.NewBufferString(content)))
+	spec, err := ParseSpec(conf)
+	t.assert.NoError(err)
+	spec.TypeResolver = t.types
+	t.assert.NoError(spec.Resolve())
+	return spec.Validate()
+}
+
+func TestValidateSpec(t *testing.T) {
+	tester := makeTester(t)
+	tester.addTypes(typeValidateCtl, typeValidateUser, typeInstanceA)
+	assert.NoError(t, tester.validate(`---
+        name: test
+        components:
+          ctl:
+            type: test.validate.ctl
+            config:
+              param: 1
+          user:
+            type: test.validate.user
+            inject:
+              ctl:
+                type: ref
+                id: ctl
+              remote:
+                type: hub
+                path: remote/component/endpoint
+     `))
+
+	err := tester.validate(`---
+        name: test
+        components:
+          ctl:
+            type: test.validate.ctl
+            config:
+              parm: 1
+          user:
+            type: test.validate.user
+            inject:
+              ctrl:
+                type: ref
+                id: ctl
+              remote:
+                type: ref
+                id: ctl
+          a:
+            type: test.A
+            config:
+              unchecked: 1
+     `)
+	assert.Error(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, "ctl: unknown config key parm")
+	assert.Contains(t, msg, "user: injection ctrl not accepted")
+	assert.Contains(t, msg, "user: injection remote type mismatch")
+	assert.Contains(t, msg, "user: injection ctl unresolved")
+	assert.NotContains(t, msg, "unchecked")
+}
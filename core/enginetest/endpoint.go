@@ -0,0 +1,191 @@
+package enginetest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// EndpointRef is an in-memory mqhub.EndpointRef recording the consumed
+// messages and delivering them to the watchers
+type EndpointRef struct {
+	// Error is returned by ConsumeMessage if set
+	Error error
+
+	lock     sync.Mutex
+	messages []mqhub.Message
+	watchers map[*endpointWatcher]struct{}
+	notify   chan struct{}
+}
+
+type endpointWatcher struct {
+	ref  *EndpointRef
+	sink mqhub.MessageSink
+}
+
+// ConsumeMessage implements mqhub.MessageSink
+func (r *EndpointRef) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	r.lock.Lock()
+	r.messages = append(r.messages, msg)
+	sinks := make([]mqhub.MessageSink, 0, len(r.watchers))
+	for w := range r.watchers {
+		sinks = append(sinks, w.sink)
+	}
+	if r.notify != nil {
+		close(r.notify)
+		r.notify = nil
+	}
+	err := r.Error
+	r.lock.Unlock()
+	for _, sink := range sinks {
+		sink.ConsumeMessage(msg)
+	}
+	return &mqhub.ImmediateFuture{Error: err}
+}
+
+// Watch implements mqhub.Watchable
+func (r *EndpointRef) Watch(sink mqhub.MessageSink) (mqhub.Watcher, error) {
+	w := &endpointWatcher{ref: r, sink: sink}
+	r.lock.Lock()
+	if r.watchers == nil {
+		r.watchers = make(map[*endpointWatcher]struct{})
+	}
+	r.watchers[w] = struct{}{}
+	r.lock.Unlock()
+	return w, nil
+}
+
+// Close implements mqhub.Watcher
+func (w *endpointWatcher) Close() error {
+	w.ref.lock.Lock()
+	delete(w.ref.watchers, w)
+	w.ref.lock.Unlock()
+	return nil
+}
+
+// Publish delivers a value to the watchers, like a DataPoint update
+func (r *EndpointRef) Publish(v interface{}) error {
+	return r.ConsumeMessage(mqhub.MsgFrom(v)).Wait()
+}
+
+// Watchers returns the number of active watchers
+func (r *EndpointRef) Watchers() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.watchers)
+}
+
+// Messages returns the consumed messages
+func (r *EndpointRef) Messages() []mqhub.Message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]mqhub.Message(nil), r.messages...)
+}
+
+// Len returns the number of consumed messages
+func (r *EndpointRef) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.messages)
+}
+
+// Last decodes the last consumed message into out
+func (r *EndpointRef) Last(out interface{}) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if len(r.messages) == 0 {
+		return fmt.Errorf("no message")
+	}
+	return r.messages[len(r.messages)-1].As(out)
+}
+
+// Reset clears the consumed messages
+func (r *EndpointRef) Reset() {
+	r.lock.Lock()
+	r.messages = nil
+	r.lock.Unlock()
+}
+
+// WaitLen waits until at least n messages are consumed
+func (r *EndpointRef) WaitLen(n int, timeout time.Duration) error {
+	deadline := time.After(timeout)
+	for {
+		r.lock.Lock()
+		count := len(r.messages)
+		if r.notify == nil {
+			r.notify = make(chan struct{})
+		}
+		notify := r.notify
+		r.lock.Unlock()
+		if count >= n {
+			return nil
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return fmt.Errorf("expect %d messages, got %d in %v", n, count, timeout)
+		}
+	}
+}
+
+// Probe drives the Reactors of a component and observes its DataPoints
+type Probe struct {
+	reactors   map[string]mqhub.MessageSink
+	dataPoints map[string]*mqhub.DataPoint
+	updates    map[string]*EndpointRef
+}
+
+// NewProbe attaches to the endpoints of the component, the DataPoint
+// updates are recorded instead of being published
+func NewProbe(comp v0.Component) *Probe {
+	p := &Probe{
+		reactors:   make(map[string]mqhub.MessageSink),
+		dataPoints: make(map[string]*mqhub.DataPoint),
+		updates:    make(map[string]*EndpointRef),
+	}
+	stateful, ok := comp.(v0.Stateful)
+	if !ok {
+		return p
+	}
+	for _, endpoint := range stateful.Endpoints() {
+		switch ep := endpoint.(type) {
+		case *mqhub.DataPoint:
+			updates := &EndpointRef{}
+			ep.Sink = updates
+			p.dataPoints[ep.Name] = ep
+			p.updates[ep.Name] = updates
+		case mqhub.MessageSink:
+			p.reactors[endpoint.ID()] = ep
+		}
+	}
+	return p
+}
+
+// Send sends a value to the named Reactor and waits for the result
+func (p *Probe) Send(reactor string, v interface{}) error {
+	sink := p.reactors[reactor]
+	if sink == nil {
+		return fmt.Errorf("unknown reactor %s", reactor)
+	}
+	return sink.ConsumeMessage(mqhub.MsgFrom(v)).Wait()
+}
+
+// DataPoint returns the recorded updates of the named DataPoint,
+// it panics if the DataPoint doesn't exist
+func (p *Probe) DataPoint(name string) *EndpointRef {
+	updates := p.updates[name]
+	if updates == nil {
+		panic("unknown DataPoint " + name)
+	}
+	return updates
+}
+
+// Close detaches from the DataPoints
+func (p *Probe) Close() {
+	for _, dp := range p.dataPoints {
+		dp.Sink = nil
+	}
+}
@@ -0,0 +1,134 @@
+package enginetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/contract/v0"
+	eng "github.com/robotalks/talk/core/engine"
+	"github.com/stretchr/testify/assert"
+	"gobot.io/x/gobot/drivers/aio"
+	"gobot.io/x/gobot/drivers/gpio"
+	"gobot.io/x/gobot/drivers/i2c"
+)
+
+type testComp struct {
+	Pin     string      `map:"pin"`
+	Adapter interface{} `inject:"io" map:"-"`
+
+	ref   v0.ComponentRef
+	value *mqhub.DataPoint
+	set   *mqhub.Reactor
+}
+
+var testType = eng.DefineComponentType("test.comp",
+	eng.ComponentFactoryFunc(func(ref v0.ComponentRef) (v0.Component, error) {
+		s := &testComp{ref: ref, value: &mqhub.DataPoint{Name: "value"}}
+		s.set = mqhub.ReactorAs("set", func(v int) { s.value.Update(v * 2) })
+		if err := eng.SetupComponent(s, ref); err != nil {
+			return nil, err
+		}
+		v0.LoggerOf(ref).Info("created", "pin", s.Pin)
+		return s, nil
+	})).Prototype(&testComp{})
+
+func (s *testComp) Ref() v0.ComponentRef        { return s.ref }
+func (s *testComp) Type() v0.ComponentType      { return testType }
+func (s *testComp) Endpoints() []mqhub.Endpoint { return []mqhub.Endpoint{s.value, s.set} }
+
+func TestRef(t *testing.T) {
+	parent := NewRef("robot")
+	ref := NewRef("led").WithParent(parent).Set("pin", "7").Inject("io", NewAdaptor("gpio"))
+	assert.Equal(t, "robot/led", ref.MessagePath())
+	assert.Equal(t, []v0.ComponentRef{ref}, parent.Children())
+	assert.True(t, parent == ref.Parent())
+	assert.Nil(t, parent.Parent())
+
+	comp, err := ref.Create(testType)
+	assert.NoError(t, err)
+	assert.Equal(t, "7", comp.(*testComp).Pin)
+	assert.True(t, comp == ref.Component())
+	if entries := ref.Logs().Entries(); assert.Len(t, entries, 1) {
+		assert.Equal(t, LogEntry{Level: v0.LogInfo, Message: "created", Fields: []interface{}{"pin", "7"}}, entries[0])
+	}
+	ref.Logger().With("a", 1).Warn("w", "b", 2)
+	assert.Equal(t, []interface{}{"a", 1, "b", 2}, ref.Logs().Entries()[1].Fields)
+
+	_, err = NewRef("bad").Set("pins", "7").Inject("io", 1).Create(testType)
+	assert.Error(t, err)
+	_, err = NewRef("missing").Create(testType)
+	assert.Error(t, err)
+
+	eng.ReportExit(comp, nil)
+	assert.Equal(t, []error{nil}, ref.Exits())
+}
+
+func TestProbe(t *testing.T) {
+	comp, err := NewRef("comp").Inject("io", 1).Create(testType)
+	assert.NoError(t, err)
+	probe := NewProbe(comp)
+	assert.NoError(t, probe.Send("set", 3))
+	assert.Error(t, probe.Send("unknown", 3))
+	var v int
+	assert.NoError(t, probe.DataPoint("value").Last(&v))
+	assert.Equal(t, 6, v)
+	assert.Panics(t, func() { probe.DataPoint("unknown") })
+	probe.Close()
+	assert.Nil(t, comp.(*testComp).value.Sink)
+}
+
+func TestEndpointRef(t *testing.T) {
+	ref := &EndpointRef{}
+	var received []int
+	w, err := ref.Watch(mqhub.MessageSinkAs(func(v int) { received = append(received, v) }))
+	assert.NoError(t, err)
+	assert.Error(t, ref.Last(new(int)))
+	assert.NoError(t, ref.Publish(1))
+	assert.Equal(t, []int{1}, received)
+	assert.Equal(t, 1, ref.Watchers())
+	w.Close()
+	assert.Equal(t, 0, ref.Watchers())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ref.Publish(2)
+	}()
+	assert.NoError(t, ref.WaitLen(2, time.Second))
+	assert.Len(t, ref.Messages(), 2)
+	assert.Error(t, ref.WaitLen(3, 10*time.Millisecond))
+	ref.Reset()
+	assert.Equal(t, 0, ref.Len())
+}
+
+func TestAdaptor(t *testing.T) {
+	a := NewAdaptor("fake")
+	var (
+		_ gpio.DigitalWriter = a
+		_ gpio.DigitalReader = a
+		_ gpio.PwmWriter     = a
+		_ gpio.ServoWriter   = a
+		_ aio.AnalogReader   = a
+		_ i2c.Connector      = a
+	)
+	led := gpio.NewLedDriver(a, "7")
+	assert.NoError(t, led.On())
+	assert.NoError(t, led.Brightness(128))
+	assert.Equal(t, []PinWrite{{WriteDigital, "7", 1}, {WritePWM, "7", 128}}, a.Writes())
+	assert.Equal(t, 1, a.Digital("7"))
+	assert.Equal(t, 128, a.Pins().PWM["7"])
+
+	a.SetAnalog("A0", 512)
+	v, err := a.AnalogRead("A0")
+	assert.NoError(t, err)
+	assert.Equal(t, 512, v)
+
+	pca := i2c.NewPCA9685Driver(a)
+	assert.NoError(t, pca.Start())
+	assert.NoError(t, pca.SetPWM(1, 0, 0x0123))
+	dev := a.I2cDevice(0x40)
+	assert.Equal(t, byte(0x23), dev.Register(0x0c))
+	assert.Equal(t, byte(0x01), dev.Register(0x0d))
+	assert.Len(t, dev.Writes(), 2)
+	assert.Equal(t, 0x23, a.Registers()["0x40"]["0x0c"])
+}
@@ -0,0 +1,425 @@
+package enginetest
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/drivers/i2c"
+)
+
+// Pin write kinds
+const (
+	WriteDigital = "digital"
+	WritePWM     = "pwm"
+	WriteServo   = "servo"
+)
+
+// PinWrite is a write recorded by Adaptor
+type PinWrite struct {
+	Kind  string
+	Pin   string
+	Value int
+}
+
+// PinState is the state of the pins of Adaptor
+type PinState struct {
+	Digital map[string]int `json:"digital"`
+	PWM     map[string]int `json:"pwm"`
+	Servo   map[string]int `json:"servo"`
+	Analog  map[string]int `json:"analog"`
+}
+
+// Adaptor is a fake gobot adaptor, it implements gpio.DigitalWriter,
+// gpio.DigitalReader, gpio.PwmWriter, gpio.ServoWriter, aio.AnalogReader
+// and i2c.Connector. The pin writes are recorded and the reads return the
+// values set by SetDigital and SetAnalog. It can be injected directly as
+// the adapter of gobot components.
+type Adaptor struct {
+	// Error is returned by all pin operations if set
+	Error error
+	// Bus is the default I2C bus
+	Bus int
+	// OnPinChange is called after a pin changes
+	OnPinChange func()
+	// OnRegisterChange is called after I2C registers are written
+	OnRegisterChange func()
+
+	lock      sync.Mutex
+	name      string
+	connected bool
+	pins      PinState
+	writes    []PinWrite
+	devices   map[int]*I2cDevice
+}
+
+// NewAdaptor creates an Adaptor
+func NewAdaptor(name string) *Adaptor {
+	return &Adaptor{
+		name: name,
+		pins: PinState{
+			Digital: make(map[string]int),
+			PWM:     make(map[string]int),
+			Servo:   make(map[string]int),
+			Analog:  make(map[string]int),
+		},
+		devices: make(map[int]*I2cDevice),
+	}
+}
+
+// Adaptor returns itself to implement the adapter of gobot components
+func (a *Adaptor) Adaptor() gobot.Adaptor {
+	return a
+}
+
+// Name implements gobot.Adaptor
+func (a *Adaptor) Name() string {
+	return a.name
+}
+
+// SetName implements gobot.Adaptor
+func (a *Adaptor) SetName(n string) {
+	a.name = n
+}
+
+// Connect implements gobot.Adaptor
+func (a *Adaptor) Connect() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.connected = true
+	return nil
+}
+
+// Finalize implements gobot.Adaptor
+func (a *Adaptor) Finalize() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.connected = false
+	return nil
+}
+
+// Connected determines if Connect is called without Finalize
+func (a *Adaptor) Connected() bool {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.connected
+}
+
+// DigitalWrite implements gpio.DigitalWriter
+func (a *Adaptor) DigitalWrite(pin string, level byte) error {
+	return a.write(WriteDigital, pin, int(level))
+}
+
+// PwmWrite implements gpio.PwmWriter
+func (a *Adaptor) PwmWrite(pin string, level byte) error {
+	return a.write(WritePWM, pin, int(level))
+}
+
+// ServoWrite implements gpio.ServoWriter
+func (a *Adaptor) ServoWrite(pin string, angle byte) error {
+	return a.write(WriteServo, pin, int(angle))
+}
+
+// DigitalRead implements gpio.DigitalReader
+func (a *Adaptor) DigitalRead(pin string) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.pins.Digital[pin], a.Error
+}
+
+// AnalogRead implements aio.AnalogReader
+func (a *Adaptor) AnalogRead(pin string) (int, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.pins.Analog[pin], a.Error
+}
+
+// SetDigital sets the level returned by DigitalRead
+func (a *Adaptor) SetDigital(pin string, level int) {
+	a.lock.Lock()
+	changed := setPin(a.pins.Digital, pin, level)
+	a.lock.Unlock()
+	a.pinChanged(changed)
+}
+
+// SetAnalog sets the value returned by AnalogRead
+func (a *Adaptor) SetAnalog(pin string, value int) {
+	a.lock.Lock()
+	changed := setPin(a.pins.Analog, pin, value)
+	a.lock.Unlock()
+	a.pinChanged(changed)
+}
+
+// Digital returns the current level of the pin
+func (a *Adaptor) Digital(pin string) int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.pins.Digital[pin]
+}
+
+// Pins returns a copy of the pin state
+func (a *Adaptor) Pins() PinState {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return PinState{
+		Digital: copyPins(a.pins.Digital),
+		PWM:     copyPins(a.pins.PWM),
+		Servo:   copyPins(a.pins.Servo),
+		Analog:  copyPins(a.pins.Analog),
+	}
+}
+
+// Writes returns the recorded pin writes
+func (a *Adaptor) Writes() []PinWrite {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]PinWrite(nil), a.writes...)
+}
+
+// Reset clears the recorded pin writes
+func (a *Adaptor) Reset() {
+	a.lock.Lock()
+	a.writes = nil
+	a.lock.Unlock()
+}
+
+func (a *Adaptor) write(kind, pin string, value int) error {
+	a.lock.Lock()
+	if a.Error != nil {
+		a.lock.Unlock()
+		return a.Error
+	}
+	a.writes = append(a.writes, PinWrite{Kind: kind, Pin: pin, Value: value})
+	var changed bool
+	switch kind {
+	case WriteDigital:
+		changed = setPin(a.pins.Digital, pin, value)
+	case WritePWM:
+		changed = setPin(a.pins.PWM, pin, value)
+	case WriteServo:
+		changed = setPin(a.pins.Servo, pin, value)
+	}
+	a.lock.Unlock()
+	a.pinChanged(changed)
+	return nil
+}
+
+func (a *Adaptor) pinChanged(changed bool) {
+	if changed && a.OnPinChange != nil {
+		a.OnPinChange()
+	}
+}
+
+func setPin(pins map[string]int, pin string, value int) bool {
+	old, exists := pins[pin]
+	pins[pin] = value
+	return !exists || old != value
+}
+
+func copyPins(pins map[string]int) map[string]int {
+	copied := make(map[string]int, len(pins))
+	for pin, value := range pins {
+		copied[pin] = value
+	}
+	return copied
+}
+
+// GetConnection implements i2c.Connector
+func (a *Adaptor) GetConnection(address int, bus int) (i2c.Connection, error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.Error != nil {
+		return nil, a.Error
+	}
+	return a.device(address), nil
+}
+
+// GetDefaultBus implements i2c.Connector
+func (a *Adaptor) GetDefaultBus() int {
+	return a.Bus
+}
+
+// I2cDevice returns the fake device at the address
+func (a *Adaptor) I2cDevice(address int) *I2cDevice {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return a.device(address)
+}
+
+// Registers returns the registers of all I2C devices,
+// keyed by the hex address of devices and registers
+func (a *Adaptor) Registers() map[string]map[string]int {
+	a.lock.Lock()
+	devices := make([]*I2cDevice, 0, len(a.devices))
+	for _, dev := range a.devices {
+		devices = append(devices, dev)
+	}
+	a.lock.Unlock()
+	registers := make(map[string]map[string]int, len(devices))
+	for _, dev := range devices {
+		dev.lock.Lock()
+		values := make(map[string]int, len(dev.registers))
+		for reg, value := range dev.registers {
+			values[fmt.Sprintf("0x%02x", reg)] = int(value)
+		}
+		dev.lock.Unlock()
+		registers[fmt.Sprintf("0x%02x", dev.Address)] = values
+	}
+	return registers
+}
+
+func (a *Adaptor) device(address int) *I2cDevice {
+	dev := a.devices[address]
+	if dev == nil {
+		dev = &I2cDevice{Address: address, adaptor: a, registers: make(map[uint8]byte)}
+		a.devices[address] = dev
+	}
+	return dev
+}
+
+// I2cWrite is a write recorded by I2cDevice, Reg is -1 for raw writes
+type I2cWrite struct {
+	Reg  int
+	Data []byte
+}
+
+// I2cDevice is a fake i2c.Connection with registers,
+// register writes are recorded and update the registers.
+// A raw write selects the register by the first byte and writes
+// the rest from there, a raw read continues from the selected register.
+type I2cDevice struct {
+	Address int
+	// Input is read by Read and ReadByte before the registers
+	Input bytes.Buffer
+
+	adaptor   *Adaptor
+	lock      sync.Mutex
+	registers map[uint8]byte
+	cursor    uint8
+	writes    []I2cWrite
+	closed    bool
+}
+
+// Read implements io.Reader
+func (d *I2cDevice) Read(p []byte) (int, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.Input.Len() > 0 {
+		return d.Input.Read(p)
+	}
+	for n := range p {
+		p[n] = d.registers[d.cursor]
+		d.cursor++
+	}
+	return len(p), nil
+}
+
+// Write implements io.Writer
+func (d *I2cDevice) Write(p []byte) (int, error) {
+	d.record(-1, p)
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (d *I2cDevice) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.closed = true
+	return nil
+}
+
+// ReadByte implements i2c.Connection
+func (d *I2cDevice) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := d.Read(b[:])
+	return b[0], err
+}
+
+// ReadByteData implements i2c.Connection
+func (d *I2cDevice) ReadByteData(reg uint8) (uint8, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.registers[reg], nil
+}
+
+// ReadWordData implements i2c.Connection, the word is little endian
+func (d *I2cDevice) ReadWordData(reg uint8) (uint16, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return uint16(d.registers[reg]) | uint16(d.registers[reg+1])<<8, nil
+}
+
+// WriteByte implements i2c.Connection
+func (d *I2cDevice) WriteByte(val byte) error {
+	d.record(-1, []byte{val})
+	return nil
+}
+
+// WriteByteData implements i2c.Connection
+func (d *I2cDevice) WriteByteData(reg uint8, val uint8) error {
+	d.record(int(reg), []byte{val})
+	return nil
+}
+
+// WriteWordData implements i2c.Connection, the word is little endian
+func (d *I2cDevice) WriteWordData(reg uint8, val uint16) error {
+	d.record(int(reg), []byte{byte(val), byte(val >> 8)})
+	return nil
+}
+
+// WriteBlockData implements i2c.Connection
+func (d *I2cDevice) WriteBlockData(reg uint8, data []byte) error {
+	if len(data) > 32 {
+		return fmt.Errorf("block of %d bytes exceeds 32 bytes", len(data))
+	}
+	d.record(int(reg), data)
+	return nil
+}
+
+// SetRegister sets the value of a register
+func (d *I2cDevice) SetRegister(reg uint8, val byte) {
+	d.lock.Lock()
+	d.registers[reg] = val
+	d.lock.Unlock()
+}
+
+// Register returns the value of a register
+func (d *I2cDevice) Register(reg uint8) byte {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.registers[reg]
+}
+
+// Writes returns the recorded writes
+func (d *I2cDevice) Writes() []I2cWrite {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return append([]I2cWrite(nil), d.writes...)
+}
+
+// Closed determines if the device is closed
+func (d *I2cDevice) Closed() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.closed
+}
+
+func (d *I2cDevice) record(reg int, data []byte) {
+	d.lock.Lock()
+	data = append([]byte(nil), data...)
+	d.writes = append(d.writes, I2cWrite{Reg: reg, Data: data})
+	values := data
+	if reg < 0 && len(data) > 0 {
+		// raw write, select the register and move on
+		reg, values = int(data[0]), data[1:]
+		d.cursor = data[0] + uint8(len(values))
+	}
+	for n, b := range values {
+		d.registers[uint8(reg+n)] = b
+	}
+	d.lock.Unlock()
+	if len(values) > 0 && d.adaptor != nil && d.adaptor.OnRegisterChange != nil {
+		d.adaptor.OnRegisterChange()
+	}
+}
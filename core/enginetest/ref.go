@@ -0,0 +1,220 @@
+// Package enginetest provides fakes for testing components
+// without running the engine or a hub.
+package enginetest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/robotalks/talk/contract/v0"
+)
+
+// Ref is a fake v0.ComponentRef built with config, injections,
+// parent and children
+type Ref struct {
+	id         string
+	config     map[string]interface{}
+	injections map[string]interface{}
+	parent     *Ref
+	children   []v0.ComponentRef
+	component  v0.Component
+	log        *LogRecorder
+
+	lock  sync.Mutex
+	exits []error
+}
+
+// NewRef creates a Ref with the component ID
+func NewRef(id string) *Ref {
+	return &Ref{
+		id:         id,
+		config:     make(map[string]interface{}),
+		injections: make(map[string]interface{}),
+		log:        &LogRecorder{},
+	}
+}
+
+// WithConfig merges the config
+func (r *Ref) WithConfig(config map[string]interface{}) *Ref {
+	for key, value := range config {
+		r.config[key] = value
+	}
+	return r
+}
+
+// Set sets a single config value
+func (r *Ref) Set(key string, value interface{}) *Ref {
+	r.config[key] = value
+	return r
+}
+
+// Inject adds an injection, the value can be a v0.ComponentRef,
+// an mqhub.EndpointRef, or any value assignable to the inject field
+func (r *Ref) Inject(name string, value interface{}) *Ref {
+	r.injections[name] = value
+	return r
+}
+
+// WithParent sets the parent and adds the Ref to its children
+func (r *Ref) WithParent(parent *Ref) *Ref {
+	r.parent = parent
+	parent.children = append(parent.children, r)
+	return r
+}
+
+// WithChildren adds children
+func (r *Ref) WithChildren(children ...v0.ComponentRef) *Ref {
+	for _, child := range children {
+		if ref, ok := child.(*Ref); ok {
+			ref.parent = r
+		}
+		r.children = append(r.children, child)
+	}
+	return r
+}
+
+// WithComponent sets the created component, e.g. an injected dependency
+func (r *Ref) WithComponent(comp v0.Component) *Ref {
+	r.component = comp
+	return r
+}
+
+// Create creates the component of the type with this Ref
+func (r *Ref) Create(t v0.ComponentType) (v0.Component, error) {
+	comp, err := t.Factory().CreateComponent(r)
+	if err == nil {
+		r.component = comp
+	}
+	return comp, err
+}
+
+// ComponentID implements v0.ComponentRef
+func (r *Ref) ComponentID() string {
+	return r.id
+}
+
+// MessagePath implements v0.ComponentRef
+func (r *Ref) MessagePath() string {
+	if r.parent == nil {
+		return r.id
+	}
+	return strings.TrimSuffix(r.parent.MessagePath(), "/") + "/" + r.id
+}
+
+// ComponentConfig implements v0.ComponentRef
+func (r *Ref) ComponentConfig() map[string]interface{} {
+	return r.config
+}
+
+// Injections implements v0.ComponentRef
+func (r *Ref) Injections() map[string]interface{} {
+	return r.injections
+}
+
+// Component implements v0.ComponentRef
+func (r *Ref) Component() v0.Component {
+	return r.component
+}
+
+// Parent implements v0.ComponentRef
+func (r *Ref) Parent() v0.ComponentRef {
+	if r.parent == nil {
+		return nil
+	}
+	return r.parent
+}
+
+// Children implements v0.ComponentRef
+func (r *Ref) Children() []v0.ComponentRef {
+	return r.children
+}
+
+// Logger implements v0.LoggerProvider
+func (r *Ref) Logger() v0.Logger {
+	return r.log
+}
+
+// Logs returns the recorder of the logs
+func (r *Ref) Logs() *LogRecorder {
+	return r.log
+}
+
+// ReportExit implements v0.ExitReporter
+func (r *Ref) ReportExit(comp v0.Component, err error) {
+	r.lock.Lock()
+	r.exits = append(r.exits, err)
+	r.lock.Unlock()
+}
+
+// Exits returns the errors reported by ReportExit, nil for normal exits
+func (r *Ref) Exits() []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]error(nil), r.exits...)
+}
+
+// LogEntry is a log recorded by LogRecorder
+type LogEntry struct {
+	Level   v0.LogLevel
+	Message string
+	Fields  []interface{}
+}
+
+// LogRecorder implements v0.Logger by keeping the logs in memory
+type LogRecorder struct {
+	lock    sync.Mutex
+	entries []LogEntry
+}
+
+// Debug implements v0.Logger
+func (l *LogRecorder) Debug(msg string, fields ...interface{}) { l.add(v0.LogDebug, msg, fields) }
+
+// Info implements v0.Logger
+func (l *LogRecorder) Info(msg string, fields ...interface{}) { l.add(v0.LogInfo, msg, fields) }
+
+// Warn implements v0.Logger
+func (l *LogRecorder) Warn(msg string, fields ...interface{}) { l.add(v0.LogWarn, msg, fields) }
+
+// Error implements v0.Logger
+func (l *LogRecorder) Error(msg string, fields ...interface{}) { l.add(v0.LogError, msg, fields) }
+
+// With implements v0.Logger
+func (l *LogRecorder) With(fields ...interface{}) v0.Logger {
+	return &fieldLogger{recorder: l, fields: fields}
+}
+
+// Entries returns the recorded logs
+func (l *LogRecorder) Entries() []LogEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]LogEntry(nil), l.entries...)
+}
+
+func (l *LogRecorder) add(level v0.LogLevel, msg string, fields []interface{}) {
+	l.lock.Lock()
+	l.entries = append(l.entries, LogEntry{Level: level, Message: msg, Fields: fields})
+	l.lock.Unlock()
+}
+
+// fieldLogger prepends the fields to the logs
+type fieldLogger struct {
+	recorder *LogRecorder
+	fields   []interface{}
+}
+
+func (l *fieldLogger) Debug(msg string, fields ...interface{}) { l.add(v0.LogDebug, msg, fields) }
+func (l *fieldLogger) Info(msg string, fields ...interface{})  { l.add(v0.LogInfo, msg, fields) }
+func (l *fieldLogger) Warn(msg string, fields ...interface{})  { l.add(v0.LogWarn, msg, fields) }
+func (l *fieldLogger) Error(msg string, fields ...interface{}) { l.add(v0.LogError, msg, fields) }
+
+func (l *fieldLogger) With(fields ...interface{}) v0.Logger {
+	return &fieldLogger{recorder: l.recorder, fields: l.join(fields)}
+}
+
+func (l *fieldLogger) add(level v0.LogLevel, msg string, fields []interface{}) {
+	l.recorder.add(level, msg, l.join(fields))
+}
+
+func (l *fieldLogger) join(fields []interface{}) []interface{} {
+	return append(append([]interface{}(nil), l.fields...), fields...)
+}
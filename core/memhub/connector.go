@@ -0,0 +1,169 @@
+package memhub
+
+import (
+	"sync"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// Connector implements mqhub.Connector on a Hub
+type Connector struct {
+	hub *Hub
+
+	lock         sync.Mutex
+	publications map[*publication]struct{}
+}
+
+// Hub returns the hub the connector attaches to
+func (c *Connector) Hub() *Hub {
+	return c.hub
+}
+
+// Connect implements mqhub.Connector
+func (c *Connector) Connect() mqhub.Future {
+	return &mqhub.ImmediateFuture{}
+}
+
+// Close implements mqhub.Connector
+func (c *Connector) Close() error {
+	c.lock.Lock()
+	pubs := c.publications
+	c.publications = nil
+	c.lock.Unlock()
+	for pub := range pubs {
+		pub.unpublish()
+	}
+	c.hub.unwatchAll(c)
+	return nil
+}
+
+// Watch implements mqhub.Connector, it watches all messages on the hub
+func (c *Connector) Watch(sink mqhub.MessageSink) (mqhub.Watcher, error) {
+	return c.hub.watch("", true, sink, c), nil
+}
+
+// Publish implements mqhub.Connector
+func (c *Connector) Publish(comp mqhub.Component) (mqhub.Publication, error) {
+	pub := &publication{connector: c, component: comp}
+	pub.publish("", comp)
+	c.lock.Lock()
+	if c.publications == nil {
+		c.publications = make(map[*publication]struct{})
+	}
+	c.publications[pub] = struct{}{}
+	c.lock.Unlock()
+	return pub, nil
+}
+
+// Describe implements mqhub.Connector
+func (c *Connector) Describe(componentID string) mqhub.Descriptor {
+	return &descriptor{connector: c, componentID: normalizeTopic(componentID)}
+}
+
+type publication struct {
+	connector  *Connector
+	component  mqhub.Component
+	dataPoints []*mqhub.DataPoint
+	watchers   []*watcher
+}
+
+func (p *publication) publish(parent string, comp mqhub.Component) {
+	prefix := joinTopic(parent, comp.ID())
+	for _, endpoint := range comp.Endpoints() {
+		topic := joinTopic(prefix, endpoint.ID())
+		switch ep := endpoint.(type) {
+		case *mqhub.DataPoint:
+			ep.Sink = &dataPointSink{hub: p.connector.hub, topic: topic, retain: ep.Retain}
+			p.dataPoints = append(p.dataPoints, ep)
+		case mqhub.MessageSink:
+			p.watchers = append(p.watchers, p.connector.hub.watch(topic, false, ep, p.connector))
+		}
+	}
+	if composite, ok := comp.(mqhub.Composite); ok {
+		for _, sub := range composite.Components() {
+			p.publish(prefix, sub)
+		}
+	}
+}
+
+func (p *publication) unpublish() {
+	for _, w := range p.watchers {
+		w.Close()
+	}
+	for _, dp := range p.dataPoints {
+		dp.Sink = nil
+	}
+	p.watchers, p.dataPoints = nil, nil
+}
+
+// Close implements mqhub.Publication
+func (p *publication) Close() error {
+	c := p.connector
+	c.lock.Lock()
+	delete(c.publications, p)
+	c.lock.Unlock()
+	p.unpublish()
+	return nil
+}
+
+// Component implements mqhub.Publication
+func (p *publication) Component() mqhub.Component {
+	return p.component
+}
+
+type dataPointSink struct {
+	hub    *Hub
+	topic  string
+	retain bool
+}
+
+// ConsumeMessage implements mqhub.MessageSink
+func (s *dataPointSink) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	s.hub.Dispatch(s.topic, msg, s.retain)
+	return &mqhub.ImmediateFuture{}
+}
+
+type descriptor struct {
+	connector   *Connector
+	componentID string
+}
+
+// ID implements mqhub.Descriptor
+func (d *descriptor) ID() string {
+	return d.componentID
+}
+
+// Watch implements mqhub.Descriptor, it watches all endpoints
+// of the component and its sub-components
+func (d *descriptor) Watch(sink mqhub.MessageSink) (mqhub.Watcher, error) {
+	return d.connector.hub.watch(d.componentID, true, sink, d.connector), nil
+}
+
+// SubComponent implements mqhub.Descriptor
+func (d *descriptor) SubComponent(id ...string) mqhub.Descriptor {
+	return &descriptor{
+		connector:   d.connector,
+		componentID: joinTopic(append([]string{d.componentID}, id...)...),
+	}
+}
+
+// Endpoint implements mqhub.Descriptor
+func (d *descriptor) Endpoint(name string) mqhub.EndpointRef {
+	return &endpointRef{connector: d.connector, topic: joinTopic(d.componentID, name)}
+}
+
+type endpointRef struct {
+	connector *Connector
+	topic     string
+}
+
+// ConsumeMessage implements mqhub.EndpointRef
+func (r *endpointRef) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	r.connector.hub.Dispatch(r.topic, msg, false)
+	return &mqhub.ImmediateFuture{}
+}
+
+// Watch implements mqhub.EndpointRef
+func (r *endpointRef) Watch(sink mqhub.MessageSink) (mqhub.Watcher, error) {
+	return r.connector.hub.watch(r.topic, false, sink, r.connector), nil
+}
@@ -0,0 +1,165 @@
+package memhub
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// Scheme is the URL scheme of in-memory hubs
+const Scheme = "mem"
+
+// Hub is an in-process message hub shared by connectors
+type Hub struct {
+	Name string
+
+	lock     sync.RWMutex
+	watchers map[*watcher]struct{}
+	retained map[string]mqhub.Message
+}
+
+var (
+	hubsLock sync.Mutex
+	hubs     = make(map[string]*Hub)
+)
+
+// NewHub creates a standalone Hub
+func NewHub(name string) *Hub {
+	return &Hub{
+		Name:     name,
+		watchers: make(map[*watcher]struct{}),
+		retained: make(map[string]mqhub.Message),
+	}
+}
+
+// Named returns the process-wide Hub with the name,
+// it's created if not exists
+func Named(name string) *Hub {
+	hubsLock.Lock()
+	defer hubsLock.Unlock()
+	hub := hubs[name]
+	if hub == nil {
+		hub = NewHub(name)
+		hubs[name] = hub
+	}
+	return hub
+}
+
+// NewConnector creates a connector from URL like mem://name,
+// connectors created with the same name share the same Hub
+func NewConnector(hubURL string) (*Connector, error) {
+	u, err := url.Parse(hubURL)
+	if err != nil {
+		return nil, err
+	}
+	return Named(u.Host).Connector(), nil
+}
+
+// Connector creates a connector attached to the hub
+func (h *Hub) Connector() *Connector {
+	return &Connector{hub: h}
+}
+
+// Retained returns the retained message on the topic
+func (h *Hub) Retained(topic string) mqhub.Message {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+	return h.retained[topic]
+}
+
+// Dispatch delivers a message to all watchers of the topic,
+// and keeps the message if it's retained
+func (h *Hub) Dispatch(topic string, msg mqhub.Message, retain bool) {
+	topic = normalizeTopic(topic)
+	h.lock.Lock()
+	if retain {
+		h.retained[topic] = msg
+	}
+	sinks := make([]mqhub.MessageSink, 0, len(h.watchers))
+	for w := range h.watchers {
+		if w.match(topic) {
+			sinks = append(sinks, w.sink)
+		}
+	}
+	h.lock.Unlock()
+	for _, sink := range sinks {
+		sink.ConsumeMessage(msg)
+	}
+}
+
+func (h *Hub) watch(topic string, prefix bool, sink mqhub.MessageSink, owner *Connector) *watcher {
+	w := &watcher{
+		hub:    h,
+		owner:  owner,
+		topic:  normalizeTopic(topic),
+		prefix: prefix,
+		sink:   sink,
+	}
+	h.lock.Lock()
+	h.watchers[w] = struct{}{}
+	var retained []mqhub.Message
+	for t, msg := range h.retained {
+		if w.match(t) {
+			retained = append(retained, msg)
+		}
+	}
+	h.lock.Unlock()
+	for _, msg := range retained {
+		sink.ConsumeMessage(msg)
+	}
+	return w
+}
+
+func (h *Hub) unwatch(w *watcher) {
+	h.lock.Lock()
+	delete(h.watchers, w)
+	h.lock.Unlock()
+}
+
+func (h *Hub) unwatchAll(owner *Connector) {
+	h.lock.Lock()
+	for w := range h.watchers {
+		if w.owner == owner {
+			delete(h.watchers, w)
+		}
+	}
+	h.lock.Unlock()
+}
+
+type watcher struct {
+	hub    *Hub
+	owner  *Connector
+	topic  string
+	prefix bool
+	sink   mqhub.MessageSink
+}
+
+func (w *watcher) match(topic string) bool {
+	if !w.prefix {
+		return topic == w.topic
+	}
+	return w.topic == "" || topic == w.topic ||
+		strings.HasPrefix(topic, w.topic+"/")
+}
+
+// Close implements mqhub.Watcher
+func (w *watcher) Close() error {
+	w.hub.unwatch(w)
+	return nil
+}
+
+func normalizeTopic(topic string) string {
+	return strings.Trim(topic, "/")
+}
+
+func joinTopic(elems ...string) string {
+	nonEmpty := make([]string, 0, len(elems))
+	for _, elem := range elems {
+		if elem = normalizeTopic(elem); elem != "" {
+			nonEmpty = append(nonEmpty, elem)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
@@ -0,0 +1,113 @@
+package memhub
+
+import (
+	"testing"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testComponent struct {
+	id        string
+	endpoints []mqhub.Endpoint
+	children  []mqhub.Component
+}
+
+func (c *testComponent) ID() string                    { return c.id }
+func (c *testComponent) Endpoints() []mqhub.Endpoint   { return c.endpoints }
+func (c *testComponent) Components() []mqhub.Component { return c.children }
+func (c *testComponent) add(comps ...mqhub.Component) *testComponent {
+	c.children = append(c.children, comps...)
+	return c
+}
+
+type testSink struct {
+	values []int
+}
+
+func (s *testSink) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	var v int
+	err := msg.As(&v)
+	if err == nil {
+		s.values = append(s.values, v)
+	}
+	return &mqhub.ImmediateFuture{Error: err}
+}
+
+func TestDataPointRetained(t *testing.T) {
+	conn := NewHub("test").Connector()
+	state := &mqhub.DataPoint{Name: "state", Retain: true}
+	pub, err := conn.Publish((&testComponent{id: "robot"}).add(
+		&testComponent{id: "led", endpoints: []mqhub.Endpoint{state}}))
+	assert.NoError(t, err)
+	state.Update(1)
+	state.Update(2)
+
+	sink := &testSink{}
+	w, err := conn.Describe("robot/led/").Endpoint("state").Watch(sink)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2}, sink.values)
+	state.Update(3)
+	assert.Equal(t, []int{2, 3}, sink.values)
+	assert.NoError(t, w.Close())
+	state.Update(4)
+	assert.Equal(t, []int{2, 3}, sink.values)
+
+	assert.NoError(t, pub.Close())
+	assert.Nil(t, state.Sink)
+}
+
+func TestReactor(t *testing.T) {
+	hub := NewHub("test")
+	var received []int
+	reactor := mqhub.ReactorAs("power", func(v int) { received = append(received, v) })
+	pub, err := hub.Connector().Publish(&testComponent{id: "led", endpoints: []mqhub.Endpoint{reactor}})
+	assert.NoError(t, err)
+
+	ref := hub.Connector().Describe("led").Endpoint("power")
+	assert.NoError(t, ref.ConsumeMessage(mqhub.MsgFrom(1)).Wait())
+	assert.Equal(t, []int{1}, received)
+
+	assert.NoError(t, pub.Close())
+	ref.ConsumeMessage(mqhub.MsgFrom(2))
+	assert.Equal(t, []int{1}, received)
+}
+
+func TestDescriptorWatch(t *testing.T) {
+	conn := NewHub("test").Connector()
+	dp0 := &mqhub.DataPoint{Name: "value"}
+	dp1 := &mqhub.DataPoint{Name: "value"}
+	_, err := conn.Publish((&testComponent{id: "a"}).add(
+		&testComponent{id: "b", endpoints: []mqhub.Endpoint{dp0}},
+		&testComponent{id: "bc", endpoints: []mqhub.Endpoint{dp1}}))
+	assert.NoError(t, err)
+
+	sink := &testSink{}
+	_, err = conn.Describe("a").SubComponent("b").Watch(sink)
+	assert.NoError(t, err)
+	dp0.Update(1)
+	dp1.Update(2)
+	assert.Equal(t, []int{1}, sink.values)
+
+	all := &testSink{}
+	_, err = conn.Watch(all)
+	assert.NoError(t, err)
+	dp0.Update(3)
+	dp1.Update(4)
+	assert.Equal(t, []int{3, 4}, all.values)
+
+	assert.NoError(t, conn.Close())
+	dp0.Update(5)
+	assert.Equal(t, []int{3, 4}, all.values)
+}
+
+func TestNamedHubs(t *testing.T) {
+	c0, err := NewConnector("mem://shared")
+	assert.NoError(t, err)
+	c1, err := NewConnector("mem://shared")
+	assert.NoError(t, err)
+	assert.True(t, c0.Hub() == c1.Hub())
+	c2, err := NewConnector("mem://")
+	assert.NoError(t, err)
+	assert.False(t, c0.Hub() == c2.Hub())
+}
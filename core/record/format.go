@@ -0,0 +1,235 @@
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// Magic is the header of a record file
+const Magic = "TALKREC1"
+
+const (
+	kindPath    = 'P'
+	kindMessage = 'M'
+
+	encodingJSON   = 'j'
+	encodingBinary = 'b'
+
+	maxEntrySize = 64 << 20
+)
+
+// ErrCorrupted indicates the record file is malformed
+var ErrCorrupted = errors.New("corrupted record file")
+
+// Entry is a recorded message
+type Entry struct {
+	// Time is the offset from the start of the recording
+	Time time.Duration
+	// Path is the full path of the endpoint, e.g. robot/vision/objects
+	Path string
+	// Binary indicates Data is raw bytes instead of JSON
+	Binary bool
+	Data   []byte
+}
+
+// EntryFrom encodes a message into an Entry
+func EntryFrom(path string, offset time.Duration, msg mqhub.Message) (*Entry, error) {
+	e := &Entry{Time: offset, Path: path}
+	if v, ok := msg.Value(); ok {
+		if data, isBytes := v.([]byte); isBytes {
+			e.Binary, e.Data = true, data
+			return e, nil
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		e.Data = data
+		return e, nil
+	}
+	var raw json.RawMessage
+	if err := msg.As(&raw); err == nil {
+		e.Data = raw
+		return e, nil
+	}
+	var data []byte
+	if err := msg.As(&data); err != nil {
+		return nil, err
+	}
+	e.Binary, e.Data = true, data
+	return e, nil
+}
+
+// Message decodes the Entry into a message
+func (e *Entry) Message() mqhub.Message {
+	if e.Binary {
+		return mqhub.StreamMessage(e.Data)
+	}
+	return mqhub.MsgFrom(json.RawMessage(e.Data))
+}
+
+// SplitPath splits the endpoint path into component ID and endpoint name
+func SplitPath(path string) (componentID, endpoint string, err error) {
+	path = strings.Trim(path, "/")
+	pos := strings.LastIndex(path, "/")
+	if pos <= 0 {
+		return "", "", fmt.Errorf("invalid endpoint path %q, expect COMPONENT/ENDPOINT", path)
+	}
+	return path[:pos], path[pos+1:], nil
+}
+
+// Writer writes entries in the compact record format,
+// the paths are written once and referenced by index,
+// the time is stored as microseconds since the previous entry
+type Writer struct {
+	Start time.Time
+
+	w     *bufio.Writer
+	paths map[string]uint64
+	last  time.Duration
+	buf   [binary.MaxVarintLen64]byte
+}
+
+// NewWriter writes the header and creates a Writer
+func NewWriter(w io.Writer, start time.Time) (*Writer, error) {
+	wr := &Writer{Start: start, w: bufio.NewWriter(w), paths: make(map[string]uint64)}
+	wr.w.WriteString(Magic)
+	wr.varint(start.UnixNano())
+	return wr, wr.w.Flush()
+}
+
+// Write writes an entry, entries must be written in time order
+func (w *Writer) Write(e *Entry) error {
+	if e.Time < w.last {
+		return fmt.Errorf("entry of %s at %v is earlier than %v", e.Path, e.Time, w.last)
+	}
+	index, ok := w.paths[e.Path]
+	if !ok {
+		index = uint64(len(w.paths))
+		w.paths[e.Path] = index
+		w.w.WriteByte(kindPath)
+		w.bytes([]byte(e.Path))
+	}
+	w.w.WriteByte(kindMessage)
+	w.uvarint(index)
+	delta := (e.Time - w.last) / time.Microsecond
+	w.uvarint(uint64(delta))
+	w.last += delta * time.Microsecond
+	if e.Binary {
+		w.w.WriteByte(encodingBinary)
+	} else {
+		w.w.WriteByte(encodingJSON)
+	}
+	return w.bytes(e.Data)
+}
+
+// Flush flushes the buffered entries
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) uvarint(v uint64) {
+	w.w.Write(w.buf[:binary.PutUvarint(w.buf[:], v)])
+}
+
+func (w *Writer) varint(v int64) {
+	w.w.Write(w.buf[:binary.PutVarint(w.buf[:], v)])
+}
+
+func (w *Writer) bytes(data []byte) error {
+	w.uvarint(uint64(len(data)))
+	_, err := w.w.Write(data)
+	return err
+}
+
+// Reader reads entries written by Writer
+type Reader struct {
+	Start time.Time
+
+	r     *bufio.Reader
+	paths []string
+	last  time.Duration
+}
+
+// NewReader reads the header and creates a Reader
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(Magic))
+	if _, err := io.ReadFull(rd.r, magic); err != nil || string(magic) != Magic {
+		return nil, fmt.Errorf("not a record file")
+	}
+	start, err := binary.ReadVarint(rd.r)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	rd.Start = time.Unix(0, start)
+	return rd, nil
+}
+
+// Next reads the next entry, io.EOF is returned at the end
+func (r *Reader) Next() (*Entry, error) {
+	for {
+		kind, err := r.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case kindPath:
+			path, err := r.bytes()
+			if err != nil {
+				return nil, err
+			}
+			r.paths = append(r.paths, string(path))
+		case kindMessage:
+			return r.message()
+		default:
+			return nil, ErrCorrupted
+		}
+	}
+}
+
+func (r *Reader) message() (*Entry, error) {
+	index, err := binary.ReadUvarint(r.r)
+	if err != nil || index >= uint64(len(r.paths)) {
+		return nil, ErrCorrupted
+	}
+	delta, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	encoding, err := r.r.ReadByte()
+	if err != nil || (encoding != encodingJSON && encoding != encodingBinary) {
+		return nil, ErrCorrupted
+	}
+	data, err := r.bytes()
+	if err != nil {
+		return nil, err
+	}
+	r.last += time.Duration(delta) * time.Microsecond
+	return &Entry{
+		Time:   r.last,
+		Path:   r.paths[index],
+		Binary: encoding == encodingBinary,
+		Data:   data,
+	}, nil
+}
+
+func (r *Reader) bytes() ([]byte, error) {
+	size, err := binary.ReadUvarint(r.r)
+	if err != nil || size > maxEntrySize {
+		return nil, ErrCorrupted
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(r.r, data); err != nil {
+		return nil, ErrCorrupted
+	}
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package record
+
+import (
+	"io"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// Player publishes the recorded messages back through the Connector
+type Player struct {
+	Connector mqhub.Connector
+	// Speed scales the original timing, e.g. 2 plays twice as fast,
+	// the messages are published without waiting if it's not positive
+	Speed float64
+
+	endpoints map[string]mqhub.EndpointRef
+}
+
+// Play publishes all entries from the Reader until the end,
+// or stop is closed, and returns the number of published messages
+func (p *Player) Play(r *Reader, stop <-chan struct{}) (int, error) {
+	start := time.Now()
+	count := 0
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, err
+		}
+		if p.Speed > 0 {
+			at := start.Add(time.Duration(float64(e.Time) / p.Speed))
+			if delay := time.Until(at); delay > 0 {
+				timer := time.NewTimer(delay)
+				select {
+				case <-timer.C:
+				case <-stop:
+					timer.Stop()
+					return count, nil
+				}
+			}
+		}
+		select {
+		case <-stop:
+			return count, nil
+		default:
+		}
+		ref, err := p.endpoint(e.Path)
+		if err != nil {
+			return count, err
+		}
+		if err = ref.ConsumeMessage(e.Message()).Wait(); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
+
+func (p *Player) endpoint(path string) (mqhub.EndpointRef, error) {
+	if ref := p.endpoints[path]; ref != nil {
+		return ref, nil
+	}
+	componentID, endpoint, err := SplitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if p.endpoints == nil {
+		p.endpoints = make(map[string]mqhub.EndpointRef)
+	}
+	ref := p.Connector.Describe(componentID).Endpoint(endpoint)
+	p.endpoints[path] = ref
+	return ref, nil
+}
@@ -0,0 +1,120 @@
+package record
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/robotalks/mqhub.go/mqhub"
+	"github.com/robotalks/talk/core/memhub"
+	"github.com/stretchr/testify/assert"
+)
+
+type testComponent struct {
+	id        string
+	endpoints []mqhub.Endpoint
+}
+
+func (c *testComponent) ID() string                  { return c.id }
+func (c *testComponent) Endpoints() []mqhub.Endpoint { return c.endpoints }
+
+type testObject struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"`
+}
+
+func TestWriterReader(t *testing.T) {
+	var buf bytes.Buffer
+	start := time.Unix(1500000000, 0)
+	w, err := NewWriter(&buf, start)
+	assert.NoError(t, err)
+	entries := []*Entry{
+		{Time: 0, Path: "robot/vision/objects", Data: []byte(`[]`)},
+		{Time: 1500 * time.Microsecond, Path: "robot/head/pan/state", Data: []byte(`90`)},
+		{Time: 2 * time.Millisecond, Path: "robot/vision/objects", Data: []byte(`[{"label":"ball"}]`)},
+		{Time: time.Second, Path: "robot/cam/frame", Binary: true, Data: []byte{0xff, 0xd8, 0xff, 0xd9}},
+	}
+	for _, e := range entries {
+		assert.NoError(t, w.Write(e))
+	}
+	assert.Error(t, w.Write(&Entry{Time: time.Millisecond, Path: "robot/vision/objects"}))
+	assert.NoError(t, w.Flush())
+
+	r, err := NewReader(&buf)
+	assert.NoError(t, err)
+	assert.True(t, start.Equal(r.Start))
+	for _, expected := range entries {
+		e, err := r.Next()
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, e)
+		}
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewReader(bytes.NewBufferString("not a record"))
+	assert.Error(t, err)
+}
+
+func TestSplitPath(t *testing.T) {
+	comp, endpoint, err := SplitPath("/robot/vision/objects")
+	assert.NoError(t, err)
+	assert.Equal(t, "robot/vision", comp)
+	assert.Equal(t, "objects", endpoint)
+	_, _, err = SplitPath("objects")
+	assert.Error(t, err)
+}
+
+func TestRecordReplay(t *testing.T) {
+	hub := memhub.NewHub("test")
+	objects := &mqhub.DataPoint{Name: "objects"}
+	frame := &mqhub.DataPoint{Name: "frame"}
+	_, err := hub.Connector().Publish(&testComponent{id: "vision", endpoints: []mqhub.Endpoint{objects, frame}})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, time.Now())
+	assert.NoError(t, err)
+	recorder := &Recorder{Connector: hub.Connector(), Writer: w}
+	assert.NoError(t, recorder.Watch("vision/objects", "vision/frame"))
+	assert.Error(t, recorder.Watch("vision"))
+	objects.Update([]testObject{{Label: "ball", Score: 0.9}})
+	time.Sleep(20 * time.Millisecond)
+	frame.Update(mqhub.StreamMessage([]byte{1, 2, 3}))
+	objects.Update([]testObject{})
+	assert.NoError(t, recorder.Close())
+	assert.Equal(t, 3, recorder.Count())
+	objects.Update([]testObject{{Label: "cup"}})
+
+	replayed := memhub.NewHub("replay")
+	var received [][]testObject
+	var frames [][]byte
+	var times []time.Time
+	replayed.Connector().Describe("vision").Endpoint("objects").Watch(
+		mqhub.MessageSinkAs(func(objs []testObject) {
+			received = append(received, objs)
+			times = append(times, time.Now())
+		}))
+	replayed.Connector().Describe("vision").Endpoint("frame").Watch(
+		mqhub.MessageSinkAs(func(data []byte) { frames = append(frames, data) }))
+
+	r, err := NewReader(bytes.NewReader(buf.Bytes()))
+	assert.NoError(t, err)
+	player := &Player{Connector: replayed.Connector(), Speed: 2}
+	count, err := player.Play(r, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, [][]testObject{{{Label: "ball", Score: 0.9}}, {}}, received)
+	assert.Equal(t, [][]byte{{1, 2, 3}}, frames)
+	if assert.Len(t, times, 2) {
+		assert.True(t, times[1].Sub(times[0]) >= 9*time.Millisecond)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	r, _ = NewReader(bytes.NewReader(buf.Bytes()))
+	count, err = (&Player{Connector: replayed.Connector(), Speed: 1}).Play(r, stop)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,97 @@
+package record
+
+import (
+	"sync"
+	"time"
+
+	"github.com/easeway/langx.go/errors"
+	"github.com/robotalks/mqhub.go/mqhub"
+)
+
+// Recorder watches endpoints and writes the messages to a Writer
+type Recorder struct {
+	Connector mqhub.Connector
+	Writer    *Writer
+
+	lock     sync.Mutex
+	watchers []mqhub.Watcher
+	count    int
+	err      error
+}
+
+// Watch starts recording the endpoints, each path is COMPONENT/ENDPOINT
+func (r *Recorder) Watch(paths ...string) error {
+	for _, path := range paths {
+		componentID, endpoint, err := SplitPath(path)
+		if err != nil {
+			return err
+		}
+		sink := &recordSink{recorder: r, path: componentID + "/" + endpoint}
+		watcher, err := r.Connector.Describe(componentID).Endpoint(endpoint).Watch(sink)
+		if err != nil {
+			return err
+		}
+		r.lock.Lock()
+		r.watchers = append(r.watchers, watcher)
+		r.lock.Unlock()
+	}
+	return nil
+}
+
+// Count returns the number of recorded messages
+func (r *Recorder) Count() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.count
+}
+
+// Err returns the first error when writing the messages
+func (r *Recorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// Close stops watching and flushes the Writer
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	watchers := r.watchers
+	r.watchers = nil
+	r.lock.Unlock()
+	var errs errors.AggregatedError
+	for _, w := range watchers {
+		errs.Add(w.Close())
+	}
+	r.lock.Lock()
+	errs.Add(r.err)
+	errs.Add(r.Writer.Flush())
+	r.lock.Unlock()
+	return errs.Aggregate()
+}
+
+func (r *Recorder) record(path string, msg mqhub.Message) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	e, err := EntryFrom(path, time.Since(r.Writer.Start), msg)
+	if err == nil {
+		err = r.Writer.Write(e)
+	}
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return err
+	}
+	r.count++
+	return nil
+}
+
+type recordSink struct {
+	recorder *Recorder
+	path     string
+}
+
+// ConsumeMessage implements mqhub.MessageSink
+func (s *recordSink) ConsumeMessage(msg mqhub.Message) mqhub.Future {
+	return &mqhub.ImmediateFuture{Error: s.recorder.record(s.path, msg)}
+}